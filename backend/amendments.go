@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Amendment is a change made to an article by another act, as recorded in
+// the "(la 18-08-2022, Articolul 41 ... a fost modificat de ...)" lines.
+type Amendment struct {
+	Date          string `json:"date"`
+	Kind          string `json:"kind"`
+	Target        string `json:"target"`
+	TargetArticle string `json:"targetArticle,omitempty"`
+	Provision     string `json:"provision,omitempty"`
+	ActType       string `json:"actType"`
+	ActNumber     string `json:"actNumber"`
+	ActYear       string `json:"actYear"`
+	ActDate       string `json:"actDate,omitempty"`
+	GazetteNumber string `json:"gazetteNumber,omitempty"`
+	GazetteDate   string `json:"gazetteDate,omitempty"`
+	Text          string `json:"text"`
+}
+
+var (
+	amendmentRe       = regexp.MustCompile(`(?i)^\(la\s+(\d{2})-(\d{2})-(\d{4}),\s*(.+?)\s+a\s+fost\s+(modificat|abrogat|introdus|completat|înlocuit)[ăa]?\s+de\s+(.+?)\s*\)?\s*$`)
+	amendingActRe     = regexp.MustCompile(`^(?:(.+?)\s+(?:din\s+)?)?(LEGEA|ORDONANȚA DE URGENȚĂ|ORDONANȚA|RECTIFICAREA|DECIZIA CURȚII CONSTITUȚIONALE|DECRETUL|HOTĂRÂREA)\s+nr\.\s*(\d+)\s+din\s+(\d{1,2}\s+\p{L}+\s+(\d{4}))`)
+	gazetteRe         = regexp.MustCompile(`(?i)monitorul\s+oficial\s+nr\.\s*(\d+)(?:\s+(?:din|bis\s+din)\s+(\d{1,2}\s+\p{L}+\s+\d{4}))?`)
+	amendedArticleRe  = regexp.MustCompile(`(?i)\bart(?:icol(?:ul|ului)|\.)\s*(` + articleNumberPattern + `)`)
+	repealedContentRe = regexp.MustCompile(`(?i)^abrogat[ăa]?\.?$`)
+)
+
+// amendmentKinds maps the verb of an amendment line to its kind. Articles
+// "completed" with new paragraphs or letters count as insertions.
+var amendmentKinds = map[string]string{
+	"modificat": "modified",
+	"înlocuit":  "modified",
+	"abrogat":   "repealed",
+	"introdus":  "inserted",
+	"completat": "inserted",
+}
+
+var romanianMonths = map[string]int{
+	"ianuarie": 1, "februarie": 2, "martie": 3, "aprilie": 4, "mai": 5, "iunie": 6,
+	"iulie": 7, "august": 8, "septembrie": 9, "octombrie": 10, "noiembrie": 11, "decembrie": 12,
+}
+
+// romanianDate converts "17 mai 2022" to "2022-05-17", or returns "" when the
+// text is not such a date.
+func romanianDate(s string) string {
+	f := strings.Fields(s)
+	if len(f) != 3 {
+		return ""
+	}
+	day, err1 := strconv.Atoi(f[0])
+	year, err2 := strconv.Atoi(f[2])
+	month, ok := romanianMonths[strings.ToLower(f[1])]
+	if err1 != nil || err2 != nil || !ok {
+		return ""
+	}
+	return fmt.Sprintf("%04d-%02d-%02d", year, month, day)
+}
+
+// parseAmendment reads one amendment line, reporting false when the line is
+// not one.
+func parseAmendment(line string) (Amendment, bool) {
+	line = strings.TrimSpace(line)
+	m := amendmentRe.FindStringSubmatch(line)
+	if m == nil {
+		return Amendment{}, false
+	}
+	a := Amendment{
+		Date:   m[3] + "-" + m[2] + "-" + m[1],
+		Kind:   amendmentKinds[strings.ToLower(m[5])],
+		Target: strings.Join(strings.Fields(strings.ReplaceAll(m[4], " ,", ",")), " "),
+		Text:   line,
+	}
+	if am := amendedArticleRe.FindStringSubmatch(a.Target); am != nil {
+		a.TargetArticle = normalizeArticleNumber(am[1])
+	}
+	by := m[6]
+	if am := amendingActRe.FindStringSubmatch(by); am != nil {
+		a.Provision = strings.TrimSpace(am[1])
+		a.ActType = strings.TrimSpace(am[2])
+		a.ActNumber = am[3]
+		a.ActDate = romanianDate(am[4])
+		a.ActYear = am[5]
+	}
+	if gm := gazetteRe.FindStringSubmatch(by); gm != nil {
+		a.GazetteNumber = gm[1]
+		a.GazetteDate = romanianDate(gm[2])
+	}
+	return a, true
+}
+
+// extractAmendments fills Article.Amendments from the amendment lines kept in
+// the references and notes of every article, and flags repealed articles.
+func extractAmendments(code *ParsedCode) {
+	walkArticles(code, func(a *Article) {
+		a.Amendments = nil
+		var lines []string
+		lines = append(lines, a.References...)
+		for _, n := range a.Notes {
+			lines = append(lines, strings.Split(n, "\n")...)
+		}
+		for _, l := range lines {
+			if am, ok := parseAmendment(l); ok {
+				a.Amendments = append(a.Amendments, am)
+			}
+		}
+		a.Repealed = articleRepealed(a)
+	})
+	collectArticles(code)
+}
+
+// articleRepealed reports whether the article text is reduced to "Abrogat."
+// or the latest change to the article as a whole repealed it.
+func articleRepealed(a *Article) bool {
+	if repealedContentRe.MatchString(strings.TrimSpace(a.Content)) {
+		return true
+	}
+	latest := ""
+	repealed := false
+	for _, am := range a.Amendments {
+		// "Articolul 155 ... a fost abrogat", not "Alineatul (2) din Articolul 155"
+		loc := amendedArticleRe.FindStringIndex(am.Target)
+		whole := am.TargetArticle == a.Number && loc != nil && loc[0] == 0
+		if whole && am.Date >= latest {
+			latest = am.Date
+			repealed = am.Kind == "repealed"
+		}
+	}
+	return repealed
+}
+
+// ArticleChange is an amendment listed together with the article it belongs to.
+type ArticleChange struct {
+	ArticleID     string `json:"articleId"`
+	ArticleNumber string `json:"articleNumber"`
+	Repealed      bool   `json:"repealed"`
+	Amendment
+}
+
+// getAmendmentsHandler lists the changes made to a code, newest first.
+// Optional filters: since (YYYY-MM-DD), kind and limit.
+func getAmendmentsHandler(c *gin.Context) {
+	pc, err := loadParsedCode(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "code not found"})
+		return
+	}
+	since := c.Query("since")
+	kind := c.Query("kind")
+	changes := []ArticleChange{}
+	for _, a := range pc.Articles {
+		for _, am := range a.Amendments {
+			if (since != "" && am.Date < since) || (kind != "" && am.Kind != kind) {
+				continue
+			}
+			changes = append(changes, ArticleChange{ArticleID: a.ID, ArticleNumber: a.Number, Repealed: a.Repealed, Amendment: am})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Date > changes[j].Date })
+	total := len(changes)
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil && limit >= 0 && limit < len(changes) {
+		changes = changes[:limit]
+	}
+	c.JSON(http.StatusOK, gin.H{"code": pc.ID, "total": total, "changes": changes})
+}
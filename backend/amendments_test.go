@@ -0,0 +1,99 @@
+package main
+
+import "testing"
+
+func TestParseAmendment(t *testing.T) {
+	tests := []struct {
+		line string
+		want Amendment
+	}{
+		{
+			line: "(la 01-02-2014, Alin. (1) al art. 65 a fost modificat de pct. 4 al art. 245 din LEGEA nr. 187 din 24 octombrie 2012, publicată în MONITORUL OFICIAL nr. 757 din 12 noiembrie 2012. )",
+			want: Amendment{
+				Date: "2014-02-01", Kind: "modified", Target: "Alin. (1) al art. 65", TargetArticle: "65",
+				Provision: "pct. 4 al art. 245", ActType: "LEGEA", ActNumber: "187", ActYear: "2012", ActDate: "2012-10-24",
+				GazetteNumber: "757", GazetteDate: "2012-11-12",
+			},
+		},
+		{
+			line: "(la 21-12-2018, Articolul 105 din Sectiunea a 2-a , Capitolul I , Titlul III , Cartea I a fost abrogat de Punctul 12, Articolul I din LEGEA nr. 310 din 17 decembrie 2018, publicată în MONITORUL OFICIAL nr. 1074 din 18 decembrie 2018 )",
+			want: Amendment{
+				Date: "2018-12-21", Kind: "repealed", Target: "Articolul 105 din Sectiunea a 2-a, Capitolul I, Titlul III, Cartea I", TargetArticle: "105",
+				Provision: "Punctul 12, Articolul I", ActType: "LEGEA", ActNumber: "310", ActYear: "2018", ActDate: "2018-12-17",
+				GazetteNumber: "1074", GazetteDate: "2018-12-18",
+			},
+		},
+		{
+			line: "(la 23-05-2016, Alin. (9^1) al art. 68 a fost introdus de pct. 8 al art. II din ORDONANȚA DE URGENȚĂ nr. 18 din 18 mai 2016, publicată în MONITORUL OFICIAL nr. 389 din 23 mai 2016. )",
+			want: Amendment{
+				Date: "2016-05-23", Kind: "inserted", Target: "Alin. (9^1) al art. 68", TargetArticle: "68",
+				Provision: "pct. 8 al art. II", ActType: "ORDONANȚA DE URGENȚĂ", ActNumber: "18", ActYear: "2016", ActDate: "2016-05-18",
+				GazetteNumber: "389", GazetteDate: "2016-05-23",
+			},
+		},
+		{
+			line: "(la 18-08-2022, Articolul 41 din Sectiunea a 2-a , Capitolul I , Titlul II , Cartea I a fost completat de Punctul 1, Articolul 7, Capitolul II din LEGEA nr. 140 din 17 mai 2022, publicată în MONITORUL OFICIAL nr. 500 din 20 mai 2022 )",
+			want: Amendment{
+				Date: "2022-08-18", Kind: "inserted", Target: "Articolul 41 din Sectiunea a 2-a, Capitolul I, Titlul II, Cartea I", TargetArticle: "41",
+				Provision: "Punctul 1, Articolul 7, Capitolul II", ActType: "LEGEA", ActNumber: "140", ActYear: "2022", ActDate: "2022-05-17",
+				GazetteNumber: "500", GazetteDate: "2022-05-20",
+			},
+		},
+	}
+	for _, tt := range tests {
+		got, ok := parseAmendment(tt.line)
+		if !ok {
+			t.Errorf("parseAmendment(%q) found no amendment", tt.line)
+			continue
+		}
+		tt.want.Text = tt.line
+		if got != tt.want {
+			t.Errorf("parseAmendment(%q)\n got  %+v\n want %+v", tt.line, got, tt.want)
+		}
+	}
+
+	for _, line := range []string{
+		"(2) Abrogat.",
+		"Notă",
+		"(la 01-02-2014, Articolul 65 a fost ",
+	} {
+		if am, ok := parseAmendment(line); ok {
+			t.Errorf("parseAmendment(%q) = %+v, want none", line, am)
+		}
+	}
+}
+
+func TestRomanianDate(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"24 octombrie 2012", "2012-10-24"},
+		{"5 februarie 2014", "2014-02-05"},
+		{"1 Mai 2022", "2022-05-01"},
+		{"24 oct 2012", ""},
+		{"octombrie 2012", ""},
+	}
+	for _, tt := range tests {
+		if got := romanianDate(tt.in); got != tt.want {
+			t.Errorf("romanianDate(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestArticleRepealed(t *testing.T) {
+	paragraph, _ := parseAmendment("(la 21-12-2018, Alineatul (2) din Articolul 84 , Sectiunea a 4-a , Capitolul II , Titlul II , Cartea I a fost abrogat de Punctul 6, Articolul I din LEGEA nr. 310 din 17 decembrie 2018, publicată în MONITORUL OFICIAL nr. 1074 din 18 decembrie 2018 )")
+	whole, _ := parseAmendment("(la 21-12-2018, Articolul 105 din Sectiunea a 2-a , Capitolul I , Titlul III , Cartea I a fost abrogat de Punctul 12, Articolul I din LEGEA nr. 310 din 17 decembrie 2018, publicată în MONITORUL OFICIAL nr. 1074 din 18 decembrie 2018 )")
+	tests := []struct {
+		name string
+		a    Article
+		want bool
+	}{
+		{"text reduced to Abrogat", Article{Number: "105", Content: "Abrogat."}, true},
+		{"whole article repealed", Article{Number: "105", Amendments: []Amendment{whole}}, true},
+		{"one paragraph repealed", Article{Number: "84", Content: "(1) Persoanele juridice pot fi reprezentate convențional în fața instanțelor de judecată numai prin consilier juridic sau avocat, în condițiile legii.\n(2) Abrogat.", Amendments: []Amendment{paragraph}}, false},
+		{"no amendments", Article{Number: "84", Content: "(1) Persoanele juridice pot fi reprezentate convențional în fața instanțelor de judecată numai prin consilier juridic sau avocat, în condițiile legii."}, false},
+	}
+	for _, tt := range tests {
+		if got := articleRepealed(&tt.a); got != tt.want {
+			t.Errorf("%s: articleRepealed = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// articleID returns the semantic ID of an article, derived from the code and
+// the article number ("penal/art-86^1"), so it survives re-parsing.
+func articleID(codeID, number string) string {
+	return codeID + "/art-" + normalizeArticleNumber(number)
+}
+
+// articleIDs hands out the semantic IDs of one code, adding a "~2", "~3"
+// suffix when the same article number appears more than once.
+type articleIDs struct {
+	codeID string
+	seen   map[string]int
+}
+
+func newArticleIDs(codeID string) *articleIDs {
+	return &articleIDs{codeID: codeID, seen: map[string]int{}}
+}
+
+func (ids *articleIDs) next(number string) string {
+	id := articleID(ids.codeID, number)
+	ids.seen[id]++
+	if n := ids.seen[id]; n > 1 {
+		return fmt.Sprintf("%s~%d", id, n)
+	}
+	return id
+}
+
+// legacyArticleIDs replays the positional numbering used before article IDs
+// became semantic ("book_1_title_2_ch_3_sec_1_art_4") over the raw lines of a
+// code and maps every positional ID to the semantic ID of the same article.
+// The replay follows the old parser exactly, including the placeholder nodes
+// it created and the fact that headings did not close the lower levels.
+func legacyArticleIDs(lines []string, codeID string) map[string]string {
+	bookRe := regexp.MustCompile(`(?i)^Cartea`)
+	titleRe := regexp.MustCompile(`(?i)^Titlul`)
+	chapterRe := regexp.MustCompile(`(?i)^Capitolul`)
+	sectionRe := regexp.MustCompile(`(?i)^Sec[tțţ]iunea`)
+	subsectionRe := regexp.MustCompile(`(?i)^Subsec[tțţ]iunea`)
+	articleRe := regexp.MustCompile(`(?i)^Articolul\s+(\d+)\s*(?:-\s*(.+))?$`)
+
+	out := map[string]string{}
+	ids := newArticleIDs(codeID)
+	var book, title, chapter, section, subsection, article int
+	var haveBook, haveTitle, haveChapter, haveSection, inSubsection bool
+	ensureTitle := func() {
+		if !haveTitle {
+			title++
+			if !haveBook {
+				book++
+				haveBook = true
+			}
+			haveTitle = true
+		}
+	}
+	ensureChapter := func() {
+		if !haveChapter {
+			chapter++
+			ensureTitle()
+			haveChapter = true
+		}
+	}
+	ensureSection := func() {
+		if !haveSection {
+			section++
+			ensureChapter()
+			haveSection = true
+		}
+	}
+	for _, raw := range lines {
+		line := strings.TrimSpace(raw)
+		switch {
+		case line == "":
+		case bookRe.MatchString(line):
+			title, chapter, section, subsection, article = 0, 0, 0, 0, 0
+			book++
+			haveBook = true
+		case titleRe.MatchString(line):
+			chapter, section, subsection, article = 0, 0, 0, 0
+			title++
+			if !haveBook {
+				book++
+				haveBook = true
+			}
+			haveTitle = true
+		case chapterRe.MatchString(line):
+			section, subsection, article = 0, 0, 0
+			chapter++
+			ensureTitle()
+			haveChapter = true
+		case sectionRe.MatchString(line):
+			article, subsection = 0, 0
+			section++
+			ensureChapter()
+			haveSection = true
+			inSubsection = false
+		case subsectionRe.MatchString(line):
+			article = 0
+			subsection++
+			ensureSection()
+			inSubsection = true
+		case articleRe.MatchString(line):
+			if !inSubsection {
+				ensureSection()
+			}
+			article++
+			num := articleRe.FindStringSubmatch(line)[1]
+			var legacy string
+			if inSubsection {
+				legacy = fmt.Sprintf("book_%d_title_%d_ch_%d_sec_%d_sub_%d_art_%d", book, title, chapter, section, subsection, article)
+			} else {
+				legacy = fmt.Sprintf("book_%d_title_%d_ch_%d_sec_%d_art_%d", book, title, chapter, section, article)
+			}
+			out[legacy] = ids.next(num)
+		}
+	}
+	return out
+}
+
+var (
+	legacyIDsMu sync.Mutex
+	legacyIDs   map[string]map[string]string // code -> positional ID -> semantic ID
+)
+
+// loadLegacyArticleIDs builds the positional ID mapping of every known code
+// once, straight from the code texts.
+func loadLegacyArticleIDs() map[string]map[string]string {
+	legacyIDsMu.Lock()
+	defer legacyIDsMu.Unlock()
+	if legacyIDs != nil {
+		return legacyIDs
+	}
+	legacyIDs = map[string]map[string]string{}
+	for id, info := range codeFiles {
+		data, err := os.ReadFile(info.path)
+		if err != nil {
+			continue
+		}
+		legacyIDs[id] = legacyArticleIDs(strings.Split(string(data), "\n"), id)
+	}
+	return legacyIDs
+}
+
+// resolveArticleID maps an article ID to its semantic form. Semantic IDs are
+// returned unchanged. Positional IDs are looked up in codeID, or in every code
+// when codeID is empty, in which case the ID must match a single code since
+// positional IDs repeat across codes.
+func resolveArticleID(id, codeID string) (string, bool) {
+	if strings.Contains(id, "/art-") {
+		return id, true
+	}
+	if codeID != "" {
+		n, ok := loadLegacyArticleIDs()[codeID][id]
+		return n, ok
+	}
+	candidates := legacyCandidates(id)
+	if len(candidates) != 1 {
+		return "", false
+	}
+	for _, n := range candidates {
+		return n, true
+	}
+	return "", false
+}
+
+// legacyCandidates returns the semantic IDs a positional ID stands for in
+// each code that has it.
+func legacyCandidates(id string) map[string]string {
+	out := map[string]string{}
+	for code, m := range loadLegacyArticleIDs() {
+		if n, ok := m[id]; ok {
+			out[code] = n
+		}
+	}
+	return out
+}
+
+// articleCode returns the code of a semantic article ID.
+func articleCode(id string) string {
+	code, _, _ := strings.Cut(id, "/art-")
+	return code
+}
+
+// migrateArticlePrefs rewrites the positional article IDs stored in the
+// likes, favorites and saved lists to semantic IDs. A positional ID found in
+// several codes is taken from the code of the other articles of the same
+// user when only one of them has it. IDs that still cannot be resolved are
+// kept as they are and counted per code in the log.
+func migrateArticlePrefs() {
+	mu.Lock()
+	defer mu.Unlock()
+	changed := 0
+	kept := map[string]int{} // code, or "unknown", -> IDs kept
+	for _, p := range userArticlePrefs {
+		// the codes the user reads, from the IDs that resolve on their own
+		codes := map[string]bool{}
+		for _, list := range [][]string{p.Likes, p.Favorites, p.Saved} {
+			for _, id := range list {
+				if n, ok := resolveArticleID(id, ""); ok {
+					codes[articleCode(n)] = true
+				}
+			}
+		}
+		migrate := func(list []string) {
+			for i, id := range list {
+				if strings.Contains(id, "/art-") {
+					continue
+				}
+				if n, ok := resolveArticleID(id, ""); ok {
+					list[i] = n
+					changed++
+					continue
+				}
+				candidates := legacyCandidates(id)
+				var match []string
+				for code, n := range candidates {
+					if codes[code] {
+						match = append(match, n)
+					}
+				}
+				if len(match) == 1 {
+					list[i] = match[0]
+					changed++
+					continue
+				}
+				if len(candidates) == 0 {
+					kept["unknown"]++
+				}
+				for code := range candidates {
+					kept[code]++
+				}
+			}
+		}
+		migrate(p.Likes)
+		migrate(p.Favorites)
+		migrate(p.Saved)
+	}
+	if changed > 0 {
+		fmt.Println("migrated", changed, "article ids to semantic ids")
+		saveArticlePrefs()
+	}
+	if len(kept) > 0 {
+		codes := make([]string, 0, len(kept))
+		for code := range kept {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		var counts []string
+		for _, code := range codes {
+			counts = append(counts, fmt.Sprintf("%s %d", code, kept[code]))
+		}
+		fmt.Println("article ids kept unmigrated (unknown, or found in several codes):", strings.Join(counts, ", "))
+	}
+}
+
+// resolveArticleIDHandler exposes the positional to semantic ID mapping for
+// clients that still hold old IDs.
+func resolveArticleIDHandler(c *gin.Context) {
+	id := c.Query("id")
+	n, ok := resolveArticleID(id, c.Query("code"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown or ambiguous article id"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": n, "legacyId": id})
+}
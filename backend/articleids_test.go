@@ -0,0 +1,69 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The replay keeps the quirks of the old parser: a chapter heading does not
+// close the section opened before it, so the articles after it were numbered
+// in section 0.
+func TestLegacyArticleIDs(t *testing.T) {
+	lines := strings.Split("Cartea I\nTitlul I\nArticolul 1\n\nArticolul 2\nCapitolul I\nArticolul 3\nSecţiunea 1\nSubsecţiunea 1\nArticolul 4", "\n")
+	got := legacyArticleIDs(lines, "civil")
+	want := map[string]string{
+		"book_1_title_1_ch_1_sec_1_art_1":       "civil/art-1",
+		"book_1_title_1_ch_1_sec_1_art_2":       "civil/art-2",
+		"book_1_title_1_ch_2_sec_0_art_1":       "civil/art-3",
+		"book_1_title_1_ch_2_sec_1_sub_1_art_1": "civil/art-4",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("legacyArticleIDs = %v, want %v", got, want)
+	}
+}
+
+func TestMigrateArticlePrefs(t *testing.T) {
+	oldLegacy, oldPrefs, oldFile := legacyIDs, userArticlePrefs, userArticlePrefsFile
+	defer func() {
+		legacyIDs, userArticlePrefs, userArticlePrefsFile = oldLegacy, oldPrefs, oldFile
+	}()
+	// the first two positional IDs are in both codes, the third only in a
+	legacyIDs = map[string]map[string]string{
+		"a": legacyArticleIDs([]string{"Articolul 1", "Articolul 2", "Articolul 3"}, "a"),
+		"b": legacyArticleIDs([]string{"Articolul 1", "Articolul 2"}, "b"),
+	}
+	userArticlePrefsFile = filepath.Join(t.TempDir(), "user_articles.json")
+	const art1, art2, art3 = "book_1_title_1_ch_1_sec_1_art_1", "book_1_title_1_ch_1_sec_1_art_2", "book_1_title_1_ch_1_sec_1_art_3"
+	userArticlePrefs = map[string]*ArticlePrefs{
+		// art3 tells the user reads code a
+		"u1": {Likes: []string{art3, art1}},
+		// nothing tells which code art2 is from
+		"u2": {Likes: []string{art2}},
+		// semantic and unknown IDs are left alone
+		"u3": {Saved: []string{"b/art-2", "book_9_title_1_ch_1_sec_1_art_1"}},
+		// a semantic ID in another list tells the code
+		"u4": {Likes: []string{"b/art-1"}, Favorites: []string{art1}},
+	}
+	migrateArticlePrefs()
+
+	want := map[string]ArticlePrefs{
+		"u1": {Likes: []string{"a/art-3", "a/art-1"}},
+		"u2": {Likes: []string{art2}},
+		"u3": {Saved: []string{"b/art-2", "book_9_title_1_ch_1_sec_1_art_1"}},
+		"u4": {Likes: []string{"b/art-1"}, Favorites: []string{"b/art-1"}},
+	}
+	for user, w := range want {
+		if got := *userArticlePrefs[user]; !reflect.DeepEqual(got, w) {
+			t.Errorf("%s: %+v, want %+v", user, got, w)
+		}
+	}
+
+	if id, ok := resolveArticleID(art1, "b"); !ok || id != "b/art-1" {
+		t.Errorf("resolveArticleID in code b = %q, %v", id, ok)
+	}
+	if id, ok := resolveArticleID(art1, ""); ok {
+		t.Errorf("resolveArticleID in any code = %q, want ambiguous", id)
+	}
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Citation is a reference found in the text of an article that points to
+// another article, of the same code or of another code from codeFiles.
+type Citation struct {
+	Text        string `json:"text"`
+	Code        string `json:"code"`
+	Article     string `json:"article"`
+	Paragraph   string `json:"paragraph,omitempty"`
+	ToParagraph string `json:"toParagraph,omitempty"`
+	Letter      string `json:"letter,omitempty"`
+	ToLetter    string `json:"toLetter,omitempty"`
+	TargetID    string `json:"targetId,omitempty"`
+	Source      string `json:"source"`
+}
+
+var (
+	articleCiteRe   = regexp.MustCompile(`(?i)\bart(?:icolul|icolului|icolele|\.)\s*(` + articleNumberPattern + `)(?:\s*(?:alin\.|alineatul)\s*\(?(\d+(?:\^\d+)?)\)?(?:\s*-\s*\(?(\d+(?:\^\d+)?)\)?)?)?(?:\s*lit\.\s*([a-z]{1,2}(?:\^\d+)?)\))?`)
+	paragraphCiteRe = regexp.MustCompile(`(?i)\balin(?:\.|eatul|eatele)\s*\((\d+(?:\^\d+)?)\)(?:\s*-\s*\((\d+(?:\^\d+)?)\))?(?:\s*lit\.\s*([a-z]{1,2}(?:\^\d+)?)\))?`)
+	citedCodeRe     = regexp.MustCompile(`(?i)^\s*,?\s*(?:din|al|a|ale)\s+(?:(?:noul|actualul)\s+)?(cod(?:ul|ului)\s+(?:de\s+procedur[aă]\s+)?(?:penal[aă]?|civil[aă]?))(\s+(?:anterior|din\s+1969|din\s+1968|din\s+1865|din\s+1864))?`)
+	currentCodeRe   = regexp.MustCompile(`(?i)^\s*,?\s*(?:din|al|a|ale)\s+prezent(?:ul|ului)\s+cod`)
+	// the further paragraphs or letters listed after a citation, before the
+	// name of the act ("alin. (1), (2) și (3) din Legea ..."), and the end of
+	// a range of letters ("lit. d)-o)")
+	citedListRe = regexp.MustCompile(`^(?:\s*-\s*[a-z]{1,2}(?:\^\d+)?\))?(?:\s*(?:,|și|sau|ori)\s*(?:\(\d+(?:\^\d+)?\)|[a-z]{1,2}(?:\^\d+)?\))(?:\s*-\s*(?:\(\d+(?:\^\d+)?\)|[a-z]{1,2}(?:\^\d+)?\)))?)*`)
+	// one item of such a list, with the word or dash before it
+	citedItemRe   = regexp.MustCompile(`\s*(,|și|sau|ori|-)\s*(?:\((\d+(?:\^\d+)?)\)|([a-z]{1,2}(?:\^\d+)?)\))`)
+	externalActRe = regexp.MustCompile(`(?i)^\s*,?\s*(?:din|al|a|ale)\s+(?:leg|ordonan|o\.u\.g|oug|o\.g|constitu|decret|hot[aă]r|regulament|conven|directiv|tratat|cod(?:ul|ului)\s+(?:fiscal|muncii|administrativ|silvic|vamal|de\s+procedur[aă]\s+fiscal))`)
+)
+
+// citedCodeID maps the name of a code as written in the text ("Codul de
+// procedură penală") to its identifier in codeFiles.
+func citedCodeID(name string) string {
+	name = strings.ToLower(name)
+	procedure := strings.Contains(name, "procedur")
+	switch {
+	case procedure && strings.Contains(name, "penal"):
+		return "proc_penal"
+	case procedure && strings.Contains(name, "civil"):
+		return "proc_civil"
+	case strings.Contains(name, "penal"):
+		return "penal"
+	case strings.Contains(name, "civil"):
+		return "civil"
+	}
+	return ""
+}
+
+// findCitations extracts the article references of a text written in the
+// code codeID. Standalone paragraph references ("alin. (1)-(4)") point to the
+// article the text belongs to and are only considered when selfNumber is set.
+// References to other acts (laws, the Constitution, former codes) are skipped.
+func findCitations(text, codeID, selfNumber, source string) []Citation {
+	var out []Citation
+	var spans [][]int
+	for _, m := range articleCiteRe.FindAllStringSubmatchIndex(text, -1) {
+		spans = append(spans, m[:2])
+		list := citedListRe.FindStringIndex(text[m[1]:])[1]
+		rest := text[m[1]+list:]
+		target := codeID
+		end := m[1] + list
+		if cm := citedCodeRe.FindStringSubmatchIndex(rest); cm != nil {
+			if cm[4] >= 0 {
+				// former codes ("Codul penal anterior") are not in codeFiles
+				continue
+			}
+			target = citedCodeID(rest[cm[2]:cm[3]])
+			end += cm[1]
+		} else if cm := currentCodeRe.FindStringIndex(rest); cm != nil {
+			end += cm[1]
+		} else if externalActRe.MatchString(rest) {
+			continue
+		}
+		if target == "" {
+			continue
+		}
+		out = append(out, listedCitations(Citation{
+			Text:        strings.TrimSpace(text[m[0]:end]),
+			Code:        target,
+			Article:     normalizeArticleNumber(submatch(text, m, 1)),
+			Paragraph:   submatch(text, m, 2),
+			ToParagraph: submatch(text, m, 3),
+			Letter:      submatch(text, m, 4),
+			Source:      source,
+		}, text[m[1]:m[1]+list])...)
+	}
+	if selfNumber == "" {
+		return out
+	}
+	for _, m := range paragraphCiteRe.FindAllStringSubmatchIndex(text, -1) {
+		inside := false
+		for _, s := range spans {
+			if m[0] >= s[0] && m[0] < s[1] {
+				inside = true
+				break
+			}
+		}
+		if inside {
+			continue
+		}
+		list := citedListRe.FindStringIndex(text[m[1]:])[1]
+		out = append(out, listedCitations(Citation{
+			Text:        text[m[0] : m[1]+list],
+			Code:        codeID,
+			Article:     normalizeArticleNumber(selfNumber),
+			Paragraph:   submatch(text, m, 1),
+			ToParagraph: submatch(text, m, 2),
+			Letter:      submatch(text, m, 3),
+			Source:      source,
+		}, text[m[1]:m[1]+list])...)
+	}
+	return out
+}
+
+// listedCitations returns the citation c followed by one citation for each
+// further paragraph or letter listed after it: "alin. (1) lit. a), b) și
+// d)-o)" cites letters a, b and d to o of paragraph 1. Letters are only
+// listed after a cited letter, and paragraphs after a cited paragraph.
+func listedCitations(c Citation, list string) []Citation {
+	out := []Citation{c}
+	for _, m := range citedItemRe.FindAllStringSubmatch(list, -1) {
+		last := &out[len(out)-1]
+		paragraph, letter := m[2], m[3]
+		switch {
+		case m[1] == "-" && letter != "" && last.Letter != "":
+			last.ToLetter = letter
+		case m[1] == "-" && paragraph != "" && last.Paragraph != "" && last.Letter == "":
+			last.ToParagraph = paragraph
+		case m[1] == "-":
+		case letter != "" && c.Letter != "":
+			next := *last
+			next.Letter, next.ToLetter = letter, ""
+			out = append(out, next)
+		case paragraph != "" && c.Paragraph != "":
+			next := *last
+			next.Paragraph, next.ToParagraph, next.Letter, next.ToLetter = paragraph, "", "", ""
+			out = append(out, next)
+		}
+	}
+	return out
+}
+
+func submatch(text string, m []int, i int) string {
+	if m[2*i] < 0 {
+		return ""
+	}
+	return text[m[2*i]:m[2*i+1]]
+}
+
+// extractCitations fills Article.Citations for every article of the code.
+// References to the same code are resolved to article IDs right away; those
+// pointing to other codes are resolved when the citation graph is built.
+func extractCitations(code *ParsedCode) {
+	ids := articleNumberIndex(code)
+	walkArticles(code, func(a *Article) {
+		a.Citations = nil
+		cites := findCitations(a.Content, code.ID, a.Number, "content")
+		for _, n := range a.Notes {
+			cites = append(cites, findCitations(n, code.ID, "", "note")...)
+		}
+		for i := range cites {
+			if cites[i].Code == code.ID {
+				cites[i].TargetID = ids[cites[i].Article]
+			}
+		}
+		a.Citations = cites
+	})
+	collectArticles(code)
+}
+
+// articleNumberIndex maps normalized article numbers to article IDs.
+func articleNumberIndex(code *ParsedCode) map[string]string {
+	ids := make(map[string]string, len(code.Articles))
+	for _, a := range code.Articles {
+		n := normalizeArticleNumber(a.Number)
+		if _, ok := ids[n]; !ok {
+			ids[n] = a.ID
+		}
+	}
+	return ids
+}
+
+// CitationEdge is a resolved citation between two articles of the graph.
+type CitationEdge struct {
+	FromCode    string `json:"fromCode"`
+	FromArticle string `json:"fromArticle"`
+	FromID      string `json:"fromId"`
+	Citation
+}
+
+// the citation graph spans all codes, so it is built lazily on first use and
+// dropped whenever a parsed code changes
+var (
+	citationMu    sync.Mutex
+	citationBuilt bool
+	citationOut   map[string][]CitationEdge
+	citationIn    map[string][]CitationEdge
+)
+
+func articleKey(codeID, number string) string {
+	return codeID + ":" + normalizeArticleNumber(number)
+}
+
+func invalidateCitationGraph() {
+	citationMu.Lock()
+	citationBuilt = false
+	citationOut = nil
+	citationIn = nil
+	citationMu.Unlock()
+}
+
+// buildCitationGraph loads every known code and links their citations.
+// The caller must hold citationMu.
+func buildCitationGraph() {
+	out := map[string][]CitationEdge{}
+	in := map[string][]CitationEdge{}
+	indexes := map[string]map[string]string{}
+	loaded := map[string]*ParsedCode{}
+	for id := range codeFiles {
+		pc, err := loadParsedCode(id)
+		if err != nil {
+			continue
+		}
+		loaded[id] = pc
+		indexes[id] = articleNumberIndex(pc)
+	}
+	for id, pc := range loaded {
+		for _, a := range pc.Articles {
+			from := articleKey(id, a.Number)
+			for _, ct := range a.Citations {
+				if ct.TargetID == "" {
+					if idx, ok := indexes[ct.Code]; ok {
+						ct.TargetID = idx[ct.Article]
+					}
+				}
+				e := CitationEdge{FromCode: id, FromArticle: a.Number, FromID: a.ID, Citation: ct}
+				to := articleKey(ct.Code, ct.Article)
+				if to == from {
+					continue
+				}
+				out[from] = append(out[from], e)
+				if ct.TargetID != "" {
+					in[to] = append(in[to], e)
+				}
+			}
+		}
+	}
+	citationOut, citationIn = out, in
+	citationBuilt = true
+}
+
+// articleCitations returns the outgoing and incoming citations of an article.
+func articleCitations(codeID, number string) ([]CitationEdge, []CitationEdge) {
+	citationMu.Lock()
+	defer citationMu.Unlock()
+	if !citationBuilt {
+		buildCitationGraph()
+	}
+	key := articleKey(codeID, number)
+	out := append([]CitationEdge{}, citationOut[key]...)
+	in := append([]CitationEdge{}, citationIn[key]...)
+	return out, in
+}
+
+// getArticleReferencesHandler serves the citation graph around one article.
+func getArticleReferencesHandler(c *gin.Context) {
+	id := c.Param("id")
+	pc, err := loadParsedCode(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "code not found"})
+		return
+	}
+	art := findArticle(pc, c.Param("number"))
+	if art == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "article not found"})
+		return
+	}
+	out, in := articleCitations(id, art.Number)
+	c.JSON(http.StatusOK, gin.H{"id": art.ID, "number": art.Number, "outgoing": out, "incoming": in})
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindCitations(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		code   string
+		number string
+		want   []Citation
+	}{
+		{
+			name: "paragraph and letter in several codes",
+			text: "În interpretarea și aplicarea unitară a dispozițiilor art. 112 alin. (1) lit. f) din Codul penal, art. 342 alin. (6) din Codul penal și art. 549^1 din Codul de procedură penală, stabilește următoarele:",
+			code: "penal",
+			want: []Citation{
+				{Text: "art. 112 alin. (1) lit. f) din Codul penal", Code: "penal", Article: "112", Paragraph: "1", Letter: "f"},
+				{Text: "art. 342 alin. (6) din Codul penal", Code: "penal", Article: "342", Paragraph: "6"},
+				{Text: "art. 549^1 din Codul de procedură penală", Code: "proc_penal", Article: "549^1"},
+			},
+		},
+		{
+			name: "article of the same code",
+			text: "(1) Apelul incident și apelul provocat se depun de către intimat odată cu întâmpinarea la apelul principal, fiind aplicabile prevederile art. 471^1 alin. (4).",
+			code: "proc_civil",
+			want: []Citation{
+				{Text: "art. 471^1 alin. (4)", Code: "proc_civil", Article: "471^1", Paragraph: "4"},
+			},
+		},
+		{
+			name: "another code",
+			text: "(2) Măsurile provizorii necesare pentru protecția altor drepturi nepatrimoniale sunt prevăzute la art. 255 din Codul civil.",
+			code: "proc_civil",
+			want: []Citation{
+				{Text: "art. 255 din Codul civil", Code: "civil", Article: "255"},
+			},
+		},
+		{
+			name: "laws are skipped",
+			text: "Stabilește că în aplicarea legii penale mai favorabile, după judecarea definitivă a cauzei, potrivit art. 6 din Codul penal cu referire la art. 21 alin. (1), (2) și (3) din Legea nr. 187/2012:",
+			code: "penal",
+			want: []Citation{
+				{Text: "art. 6 din Codul penal", Code: "penal", Article: "6"},
+			},
+		},
+		{
+			name: "letters listed before the code",
+			text: "În interpretarea și aplicarea unitară a dispozițiilor art. 65 alin. (3) din Codul penal, stabilește că: aplicarea pedepselor accesorii constând în interzicerea drepturilor prevăzute de art. 66 alin. (1) lit. a), b) și d)-o) din Codul penal, a căror exercitare a fost interzisă de instanță ca pedeapsă complementară, nu este posibilă în cazul dispunerii unei soluții de condamnare la pedeapsa amenzii.",
+			code: "proc_penal",
+			want: []Citation{
+				{Text: "art. 65 alin. (3) din Codul penal", Code: "penal", Article: "65", Paragraph: "3"},
+				{Text: "art. 66 alin. (1) lit. a), b) și d)-o) din Codul penal", Code: "penal", Article: "66", Paragraph: "1", Letter: "a"},
+				{Text: "art. 66 alin. (1) lit. a), b) și d)-o) din Codul penal", Code: "penal", Article: "66", Paragraph: "1", Letter: "b"},
+				{Text: "art. 66 alin. (1) lit. a), b) și d)-o) din Codul penal", Code: "penal", Article: "66", Paragraph: "1", Letter: "d", ToLetter: "o"},
+			},
+		},
+		{
+			name: "paragraphs listed in the same code",
+			text: "(3) Dispozițiile art. 5 alin. (1), (2)-(4) și (6) sunt aplicabile.",
+			code: "civil",
+			want: []Citation{
+				{Text: "art. 5 alin. (1), (2)-(4) și (6)", Code: "civil", Article: "5", Paragraph: "1"},
+				{Text: "art. 5 alin. (1), (2)-(4) și (6)", Code: "civil", Article: "5", Paragraph: "2", ToParagraph: "4"},
+				{Text: "art. 5 alin. (1), (2)-(4) și (6)", Code: "civil", Article: "5", Paragraph: "6"},
+			},
+		},
+		{
+			name: "range of letters before the code",
+			text: "(2) Sunt aplicabile dispozițiile art. 1.357 lit. a)-c) din Codul civil.",
+			code: "penal",
+			want: []Citation{
+				{Text: "art. 1.357 lit. a)-c) din Codul civil", Code: "civil", Article: "1357", Letter: "a", ToLetter: "c"},
+			},
+		},
+		{
+			name: "letters of another law",
+			text: "(2) Sunt aplicabile dispozițiile art. 5 lit. a) și c) din Legea nr. 187/2012.",
+			code: "penal",
+		},
+		{
+			name: "former codes are skipped",
+			text: "Stabilește că „Fapta de sustragere de la executarea măsurii de siguranță prevăzută în art. 112 lit. d) din Codul penal anterior (din 1969) nu realizează condițiile de tipicitate ale infracțiunii prevăzute de art. 288 alin. (1) din Codul penal“.",
+			code: "penal",
+			want: []Citation{
+				{Text: "art. 288 alin. (1) din Codul penal", Code: "penal", Article: "288", Paragraph: "1"},
+			},
+		},
+		{
+			name:   "paragraph of the article itself",
+			text:   "(2) Dispozițiile alin. (1) se aplică și actelor normative ori prevederilor din acestea declarate neconstituționale, precum și ordonanțelor de urgență aprobate de Parlament cu modificări sau completări ori respinse, dacă în timpul când acestea s-au aflat în vigoare au cuprins dispoziții penale mai favorabile.",
+			code:   "penal",
+			number: "5",
+			want: []Citation{
+				{Text: "alin. (1)", Code: "penal", Article: "5", Paragraph: "1"},
+			},
+		},
+		{
+			name:   "paragraphs of the article itself",
+			text:   "(4) Dispozițiile alin. (1) și (3) se aplică în mod corespunzător.",
+			code:   "penal",
+			number: "5",
+			want: []Citation{
+				{Text: "alin. (1) și (3)", Code: "penal", Article: "5", Paragraph: "1"},
+				{Text: "alin. (1) și (3)", Code: "penal", Article: "5", Paragraph: "3"},
+			},
+		},
+		{
+			name: "paragraph without the article number",
+			text: "(2) Dispozițiile alin. (1) se aplică și actelor normative ori prevederilor din acestea declarate neconstituționale.",
+			code: "penal",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findCitations(tt.text, tt.code, tt.number, "content")
+			for i := range tt.want {
+				tt.want[i].Source = "content"
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Codul penal, art. 65 and 66: citations of the same code are resolved to
+// article IDs when the code is parsed, the others when the graph is built.
+func TestExtractCitationsTargets(t *testing.T) {
+	sec := CodeSection{Articles: []Article{
+		{
+			ID:      "art_65",
+			Number:  "65",
+			Content: "(1) Pedeapsa accesorie constă în interzicerea exercitării drepturilor prevăzute la art. 66 alin. (1) lit. a), b) și d)-o), a căror exercitare a fost interzisă de instanță ca pedeapsă complementară.",
+			Notes:   []string{"Notă\nÎn interpretarea și aplicarea unitară a dispozițiilor art. 112 alin. (1) lit. f) din Codul penal, art. 342 alin. (6) din Codul penal și art. 549^1 din Codul de procedură penală, stabilește următoarele:"},
+		},
+		{
+			ID:      "art_66",
+			Number:  "66",
+			Content: "(1) Pedeapsa complementară a interzicerii exercitării unor drepturi constă în interzicerea exercitării unuia sau a mai multora dintre următoarele drepturi:",
+		},
+	}}
+	code := &ParsedCode{ID: "penal", Books: []Book{{Titles: []CodeTitle{{Chapters: []Chapter{{Sections: []CodeSection{sec}}}}}}}}
+	collectArticles(code)
+	extractCitations(code)
+	got := code.Articles[0].Citations
+	want := []struct{ article, source, target string }{
+		{"66", "content", "art_66"},
+		{"66", "content", "art_66"},
+		{"66", "content", "art_66"},
+		{"112", "note", ""},
+		{"342", "note", ""},
+		{"549^1", "note", ""},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d citations, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		if got[i].Article != w.article || got[i].Source != w.source || got[i].TargetID != w.target {
+			t.Errorf("citation %d: got %s from %s to %q, want %s from %s to %q", i, got[i].Article, got[i].Source, got[i].TargetID, w.article, w.source, w.target)
+		}
+	}
+	if len(code.Articles[1].Citations) != 0 {
+		t.Errorf("art. 66 cites %+v", code.Articles[1].Citations)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"compress/gzip"
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// compressedTypes are the content types worth compressing; images, fonts and
+// archives are compressed already.
+var compressedTypes = map[string]bool{
+	"application/json":       true,
+	"application/javascript": true,
+	"text/javascript":        true,
+	"text/html":              true,
+	"text/css":               true,
+	"text/plain":             true,
+	"image/svg+xml":          true,
+}
+
+// brotli quality used for responses: the higher levels are too slow for the
+// JSON of a whole code built on each request
+const brotliQuality = 5
+
+// encoder is the part of gzip.Writer and brotli.Writer a response needs.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// compressWriter compresses the body of a response once it knows, at the
+// first write, that the response is worth compressing.
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+	enc      encoder
+	decided  bool
+}
+
+func (w *compressWriter) decide() {
+	w.decided = true
+	h := w.Header()
+	ct, _, _ := mime.ParseMediaType(h.Get("Content-Type"))
+	switch w.Status() {
+	case http.StatusNoContent, http.StatusPartialContent, http.StatusNotModified:
+		return
+	}
+	if !compressedTypes[ct] || h.Get("Content-Encoding") != "" {
+		return
+	}
+	h.Set("Content-Encoding", w.encoding)
+	h.Del("Content-Length")
+	// the compressed body is no longer byte for byte the tagged one
+	if etag := h.Get("ETag"); strings.HasPrefix(etag, `"`) {
+		h.Set("ETag", "W/"+etag)
+	}
+	if w.encoding == "br" {
+		w.enc = brotli.NewWriterLevel(w.ResponseWriter, brotliQuality)
+	} else {
+		w.enc = gzip.NewWriter(w.ResponseWriter)
+	}
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.decide()
+	}
+	if w.enc != nil {
+		return w.enc.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *compressWriter) Flush() {
+	if w.enc != nil {
+		w.enc.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// acceptedEncoding picks the coding of a response from the Accept-Encoding
+// header of the request: the one of br and gzip with the highest q value, br
+// on a tie, or none. Codings given q=0 are refused.
+func acceptedEncoding(header string) string {
+	quality := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			q, _ = strconv.ParseFloat(v, 64)
+		}
+		quality[strings.ToLower(strings.TrimSpace(name))] = q
+	}
+	best, encoding := 0.0, ""
+	for _, e := range []string{"br", "gzip"} {
+		if q := quality[e]; q > best {
+			best, encoding = q, e
+		}
+	}
+	return encoding
+}
+
+// compressMiddleware compresses the responses of clients accepting Brotli or
+// gzip, which makes the JSON of a parsed code ten times smaller or more.
+// WebSocket upgrades are left alone.
+func compressMiddleware(c *gin.Context) {
+	c.Header("Vary", "Accept-Encoding")
+	encoding := acceptedEncoding(c.GetHeader("Accept-Encoding"))
+	if encoding == "" || c.GetHeader("Upgrade") != "" {
+		c.Next()
+		return
+	}
+	w := &compressWriter{ResponseWriter: c.Writer, encoding: encoding}
+	c.Writer = w
+	c.Next()
+	if w.enc != nil {
+		w.enc.Close()
+	}
+}
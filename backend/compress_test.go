@@ -0,0 +1,79 @@
+package main
+
+import (
+	"compress/gzip"
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAcceptedEncoding(t *testing.T) {
+	tests := []struct{ header, want string }{
+		{"gzip, deflate, br", "br"},
+		{"gzip, deflate, br;q=0", "gzip"},
+		{"br;q=0.5, gzip;q=1.0", "gzip"},
+		{"gzip;q=0.8, br;q=0.8", "br"},
+		{"gzip", "gzip"},
+		{"GZIP", "gzip"},
+		{"deflate", ""},
+		{"gzip;q=0, br;q=0", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := acceptedEncoding(tt.header); got != tt.want {
+			t.Errorf("acceptedEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestCompressMiddleware(t *testing.T) {
+	body := strings.Repeat(`{"number":"1","content":"(1) Legea penală prevede faptele care constituie infracțiuni."}`, 50)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(compressMiddleware)
+	r.GET("/json", func(c *gin.Context) {
+		c.Header("ETag", `"0123456789abcdef0123"`)
+		c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(body))
+	})
+	r.GET("/png", func(c *gin.Context) {
+		c.Data(http.StatusOK, "image/png", []byte(body))
+	})
+
+	tests := []struct {
+		path, accept, encoding string
+		decode                 func(io.Reader) (io.Reader, error)
+	}{
+		{"/json", "gzip, deflate, br", "br", func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil }},
+		{"/json", "gzip", "gzip", func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{"/json", "", "", nil},
+		{"/png", "gzip, br", "", nil},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.Header.Set("Accept-Encoding", tt.accept)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if got := w.Header().Get("Content-Encoding"); got != tt.encoding {
+			t.Errorf("%s with %q: Content-Encoding %q, want %q", tt.path, tt.accept, got, tt.encoding)
+			continue
+		}
+		var reader io.Reader = w.Body
+		if tt.decode != nil {
+			var err error
+			if reader, err = tt.decode(w.Body); err != nil {
+				t.Fatal(err)
+			}
+			if etag := w.Header().Get("ETag"); etag != `W/"0123456789abcdef0123"` {
+				t.Errorf("%s with %q: ETag %s, want it weak", tt.path, tt.accept, etag)
+			}
+		}
+		got, err := io.ReadAll(reader)
+		if err != nil || string(got) != body {
+			t.Errorf("%s with %q: body of %d bytes (%v), want %d", tt.path, tt.accept, len(got), err, len(body))
+		}
+	}
+}
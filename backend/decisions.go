@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Decision is a court decision quoted in the notes of an article: a ruling
+// of the High Court (ÎCCJ) on a preliminary question (HP) or an appeal in the
+// interest of the law (RIL), or a decision of the Constitutional Court (CCR).
+type Decision struct {
+	ID            string `json:"id"`
+	Court         string `json:"court"`
+	Type          string `json:"type"`
+	Outcome       string `json:"outcome,omitempty"`
+	Number        string `json:"number"`
+	Year          string `json:"year"`
+	Date          string `json:"date,omitempty"`
+	GazetteNumber string `json:"gazetteNumber,omitempty"`
+	GazetteDate   string `json:"gazetteDate,omitempty"`
+	Summary       string `json:"summary"`
+}
+
+var (
+	// "Decizie de admitere: HP nr. 21/2014, publicată în Monitorul Oficial nr. 829 din 13 noiembrie 2014:"
+	iccjDecisionRe = regexp.MustCompile(`(?i)^Decizie\s+de\s+(admitere|respingere)\s*[:-]\s*(HP|RIL)\s+nr\.\s*(\d+)/(\d{4})(?:,\s*publicat[ăa]?\s+în\s+Monitorul\s+Oficial\s+(?:nr\.\s*)?(\d+)(?:\s+din\s+(\d{1,2}\s+\p{L}+\s+\d{4}))?)?\s*:?\s*(.*)$`)
+	// "Prin DECIZIA CURȚII CONSTITUȚIONALE nr. 651 din 25 octombrie 2018, publicată în MONITORUL OFICIAL nr. 1083 din 20 decembrie 2018, s-a admis ..."
+	ccrDecisionRe = regexp.MustCompile(`(?i)^Prin\s+DECIZIA\s+CUR[ȚŢT]II\s+CONSTITU[ȚŢT]IONALE\s+nr\.\s*(\d+)\s+din\s+(\d{1,2}\s+\p{L}+\s+(\d{4}))(?:,\s*publicat[ăa]?\s+în\s+MONITORUL\s+OFICIAL\s+(?:nr\.\s*)?(\d+)(?:\s+din\s+(\d{1,2}\s+\p{L}+\s+\d{4}))?)?,?\s*(.*)$`)
+)
+
+var decisionOutcomes = map[string]string{"admitere": "admitted", "respingere": "rejected"}
+
+// decisionHeader parses the first line of a decision, reporting false when
+// the line does not start one. The summary holds the rest of the line.
+func decisionHeader(line string) (Decision, bool) {
+	if m := iccjDecisionRe.FindStringSubmatch(line); m != nil {
+		typ := strings.ToUpper(m[2])
+		return Decision{
+			ID:            fmt.Sprintf("%s-%s-%s", strings.ToLower(typ), m[3], m[4]),
+			Court:         "ICCJ",
+			Type:          typ,
+			Outcome:       decisionOutcomes[strings.ToLower(m[1])],
+			Number:        m[3],
+			Year:          m[4],
+			GazetteNumber: m[5],
+			GazetteDate:   romanianDate(m[6]),
+			Summary:       m[7],
+		}, true
+	}
+	if m := ccrDecisionRe.FindStringSubmatch(line); m != nil {
+		d := Decision{
+			ID:            fmt.Sprintf("ccr-%s-%s", m[1], m[3]),
+			Court:         "CCR",
+			Type:          "CCR",
+			Number:        m[1],
+			Year:          m[3],
+			Date:          romanianDate(m[2]),
+			GazetteNumber: m[4],
+			GazetteDate:   romanianDate(m[5]),
+			Summary:       m[6],
+		}
+		rest := strings.ToLower(m[6])
+		switch {
+		case strings.Contains(rest, "a admis"), strings.Contains(rest, "constatat neconstitu"):
+			d.Outcome = "admitted"
+		case strings.Contains(rest, "a respins"):
+			d.Outcome = "rejected"
+		}
+		return d, true
+	}
+	return Decision{}, false
+}
+
+// findDecisions extracts the decisions quoted in a note. The lines following
+// a decision header make up its summary, up to the next header.
+func findDecisions(note string) []Decision {
+	var out []Decision
+	var cur *Decision
+	for _, line := range strings.Split(note, "\n") {
+		line = strings.TrimSpace(line)
+		if d, ok := decisionHeader(line); ok {
+			out = append(out, d)
+			cur = &out[len(out)-1]
+			continue
+		}
+		if cur == nil || line == "" {
+			continue
+		}
+		if noteHeadingRe.MatchString(line) || strings.HasPrefix(line, "(la ") {
+			cur = nil
+			continue
+		}
+		if cur.Summary != "" {
+			cur.Summary += "\n"
+		}
+		cur.Summary += line
+	}
+	for i := range out {
+		out[i].Summary = strings.Trim(out[i].Summary, " .")
+	}
+	return out
+}
+
+var noteHeadingRe = regexp.MustCompile(`(?i)^Not[aă]\s*:?$`)
+
+// extractDecisions fills Article.Decisions from the notes and references of
+// every article. The notes themselves are left untouched.
+func extractDecisions(code *ParsedCode) {
+	walkArticles(code, func(a *Article) {
+		a.Decisions = nil
+		seen := map[string]bool{}
+		texts := append(append([]string{}, a.Notes...), a.References...)
+		for _, t := range texts {
+			for _, d := range findDecisions(t) {
+				if seen[d.ID] {
+					continue
+				}
+				seen[d.ID] = true
+				a.Decisions = append(a.Decisions, d)
+			}
+		}
+	})
+	collectArticles(code)
+}
+
+// ArticleDecision is a decision listed together with the article quoting it.
+type ArticleDecision struct {
+	Code          string `json:"code"`
+	ArticleID     string `json:"articleId"`
+	ArticleNumber string `json:"articleNumber"`
+	Decision
+}
+
+// listDecisionsHandler serves the decisions quoted in the codes. Optional
+// filters: code, article (number, needs code), court (ICCJ or CCR), type (HP,
+// RIL, CCR), outcome (admitted, rejected) and year.
+func listDecisionsHandler(c *gin.Context) {
+	codeID := c.Query("code")
+	article := c.Query("article")
+	if article != "" && codeID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "article filter requires code"})
+		return
+	}
+	court := strings.ToUpper(strings.ReplaceAll(c.Query("court"), "Î", "I"))
+	typ := strings.ToUpper(c.Query("type"))
+	outcome := c.Query("outcome")
+	year := c.Query("year")
+
+	ids := []string{codeID}
+	if codeID == "" {
+		ids = ids[:0]
+		for id := range codeFiles {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+	}
+	out := []ArticleDecision{}
+	for _, id := range ids {
+		pc, err := loadParsedCode(id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "code not found"})
+			return
+		}
+		for _, a := range pc.Articles {
+			if article != "" && normalizeArticleNumber(article) != a.Number {
+				continue
+			}
+			for _, d := range a.Decisions {
+				if (court != "" && d.Court != court) || (typ != "" && d.Type != typ) ||
+					(outcome != "" && d.Outcome != outcome) || (year != "" && d.Year != year) {
+					continue
+				}
+				out = append(out, ArticleDecision{Code: id, ArticleID: a.ID, ArticleNumber: a.Number, Decision: d})
+			}
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"total": len(out), "decisions": out})
+}
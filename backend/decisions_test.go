@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecisionHeader(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    Decision
+		summary string
+	}{
+		{
+			line: "Decizie de admitere: HP nr. 21/2014, publicată în Monitorul Oficial nr. 829 din 13 noiembrie 2014:",
+			want: Decision{ID: "hp-21-2014", Court: "ICCJ", Type: "HP", Outcome: "admitted", Number: "21", Year: "2014", GazetteNumber: "829", GazetteDate: "2014-11-13"},
+		},
+		{
+			line: "Decizie de admitere: RIL nr. 13/2022, publicată în Monitorul Oficial nr. 907 din 15 septembrie 2022:",
+			want: Decision{ID: "ril-13-2022", Court: "ICCJ", Type: "RIL", Outcome: "admitted", Number: "13", Year: "2022", GazetteNumber: "907", GazetteDate: "2022-09-15"},
+		},
+		{
+			line:    "Decizie de respingere: HP nr. 8/2023, publicată în Monitorul Oficial nr. 219 din 16 martie 2023.",
+			want:    Decision{ID: "hp-8-2023", Court: "ICCJ", Type: "HP", Outcome: "rejected", Number: "8", Year: "2023", GazetteNumber: "219", GazetteDate: "2023-03-16"},
+			summary: ".",
+		},
+		{
+			line:    "Prin DECIZIA CURȚII CONSTITUȚIONALE nr. 265 din 6 mai 2014, publicată în MONITORUL OFICIAL nr. 372 din 20 mai 2014, s-a admis excepția de neconstituționalitate referitoare la dispozițiile art. 5 din Codul penal, constatându-se că aceste prevederi sunt constituționale în măsura în care nu permit combinarea prevederilor din legi succesive în stabilirea și aplicarea legii penale mai favorabile.",
+			want:    Decision{ID: "ccr-265-2014", Court: "CCR", Type: "CCR", Outcome: "admitted", Number: "265", Year: "2014", Date: "2014-05-06", GazetteNumber: "372", GazetteDate: "2014-05-20"},
+			summary: "s-a admis excepția de neconstituționalitate referitoare la dispozițiile art. 5 din Codul penal",
+		},
+		{
+			line:    `Prin DECIZIA CURȚII CONSTITUȚIONALE nr. 51 din 16 februarie 2016, publicată în MONITORUL OFICIAL nr. 190 din 14 martie 2016, s-a constatat neconstituționalitatea sintagmei "ori de alte organe specializate ale statului" din cuprinsul dispozițiilor art. 142 alin. (1) din Codul de procedură penală, în forma avută anterior modificării aduse prin ORDONANȚA DE URGENȚĂ nr. 6 din 11 martie 2016, publicată în MONITORUL OFICIAL nr. 190 din 14 martie 2016.`,
+			want:    Decision{ID: "ccr-51-2016", Court: "CCR", Type: "CCR", Outcome: "admitted", Number: "51", Year: "2016", Date: "2016-02-16", GazetteNumber: "190", GazetteDate: "2016-03-14"},
+			summary: "s-a constatat neconstituționalitatea sintagmei",
+		},
+	}
+	for _, tt := range tests {
+		got, ok := decisionHeader(tt.line)
+		if !ok {
+			t.Errorf("decisionHeader(%q) found no decision", tt.line)
+			continue
+		}
+		if !strings.HasPrefix(got.Summary, tt.summary) {
+			t.Errorf("decisionHeader(%q) summary %q, want it to start with %q", tt.line, got.Summary, tt.summary)
+		}
+		got.Summary = ""
+		if got != tt.want {
+			t.Errorf("decisionHeader(%q)\n got  %+v\n want %+v", tt.line, got, tt.want)
+		}
+	}
+
+	for _, line := range []string{
+		"Notă",
+		"Stabilește că dispozițiile art. 5 alin. 1 din Codul penal trebuie interpretate, inclusiv în materia prescripției răspunderii penale, în sensul că legea penală mai favorabilă este aplicabilă în cazul infracțiunilor săvârșite anterior datei de 1 februarie 2014 care nu au fost încă judecate definitiv, în conformitate cu Decizia nr. 265/2014 a Curții Constituționale.",
+		"(la 01-02-2014, Alin. (1) al art. 65 a fost modificat de pct. 4 al art. 245 din LEGEA nr. 187 din 24 octombrie 2012, publicată în MONITORUL OFICIAL nr. 757 din 12 noiembrie 2012. )",
+	} {
+		if d, ok := decisionHeader(line); ok {
+			t.Errorf("decisionHeader(%q) = %+v, want none", line, d)
+		}
+	}
+}
+
+// The note of Codul penal, art. 5 (1), followed by the header of another
+// decision: the lines after the header of a decision make up its summary.
+func TestFindDecisions(t *testing.T) {
+	note := strings.Join([]string{
+		"Notă",
+		"Decizie de admitere: HP nr. 21/2014, publicată în Monitorul Oficial nr. 829 din 13 noiembrie 2014:",
+		"",
+		"Stabilește că dispozițiile art. 5 alin. 1 din Codul penal trebuie interpretate, inclusiv în materia prescripției răspunderii penale, în sensul că legea penală mai favorabilă este aplicabilă în cazul infracțiunilor săvârșite anterior datei de 1 februarie 2014 care nu au fost încă judecate definitiv, în conformitate cu Decizia nr. 265/2014 a Curții Constituționale.",
+		"Decizie de respingere: HP nr. 8/2023, publicată în Monitorul Oficial nr. 219 din 16 martie 2023.",
+	}, "\n")
+	got := findDecisions(note)
+	if len(got) != 2 {
+		t.Fatalf("found %d decisions, want 2: %+v", len(got), got)
+	}
+	if got[0].ID != "hp-21-2014" || !strings.HasPrefix(got[0].Summary, "Stabilește că dispozițiile art. 5 alin. 1") || !strings.HasSuffix(got[0].Summary, "a Curții Constituționale") {
+		t.Errorf("first decision %+v", got[0])
+	}
+	if got[1].ID != "hp-8-2023" || got[1].Summary != "" {
+		t.Errorf("second decision %+v", got[1])
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// Diagnostic is a problem found in the source text of a code while parsing
+// it. Line is 1-based; Severity is "error", "warning" or "info".
+type Diagnostic struct {
+	Line     int    `json:"line"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// lines longer than this usually hold several paragraphs whose line breaks
+// were lost when the text was copied
+const maxLineLength = 2000
+
+// codeDiagnostics keeps the diagnostics of the last parse of each code
+// together with the fingerprint of the text they were found in.
+var (
+	diagnosticsMu   sync.Mutex
+	codeDiagnostics = map[string]struct {
+		source      sourceFingerprint
+		diagnostics []Diagnostic
+	}{}
+)
+
+func storeDiagnostics(id string, source sourceFingerprint, diagnostics []Diagnostic) {
+	diagnosticsMu.Lock()
+	defer diagnosticsMu.Unlock()
+	codeDiagnostics[id] = struct {
+		source      sourceFingerprint
+		diagnostics []Diagnostic
+	}{source, diagnostics}
+}
+
+// diagnosticsFor returns the diagnostics of the current source text of a
+// code. They come from the startup parsing when the text has not changed
+// since; otherwise the text is parsed again and the result kept.
+func diagnosticsFor(id string) ([]Diagnostic, error) {
+	info, ok := codeFiles[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown code id")
+	}
+	source, err := statSource(info.path)
+	if err != nil {
+		return nil, err
+	}
+	diagnosticsMu.Lock()
+	stored, ok := codeDiagnostics[id]
+	diagnosticsMu.Unlock()
+	if ok && stored.source.sameFile(source) {
+		return stored.diagnostics, nil
+	}
+	pc, err := parseCodeFile(info.path, id, info.title)
+	if err != nil {
+		return nil, err
+	}
+	storeDiagnostics(id, source, pc.Diagnostics)
+	return pc.Diagnostics, nil
+}
+
+func addDiagnostic(code *ParsedCode, line int, severity, format string, args ...interface{}) {
+	code.Diagnostics = append(code.Diagnostics, Diagnostic{Line: line, Severity: severity, Message: fmt.Sprintf(format, args...)})
+}
+
+// articleNumberingDiagnostics reports duplicate numbers, gaps and numbers out
+// of order in the articles of a code. lines maps article IDs to the line of
+// their heading.
+func articleNumberingDiagnostics(code *ParsedCode, lines map[string]int) {
+	first := map[string]string{}
+	prev := -1
+	prevNumber := ""
+	for _, a := range code.Articles {
+		line := lines[a.ID]
+		if id, ok := first[a.Number]; ok {
+			addDiagnostic(code, line, "warning", "duplicate article number %s, first seen at line %d", a.Number, lines[id])
+		} else {
+			first[a.Number] = a.ID
+		}
+		base := a.SortKey / 1000
+		switch {
+		case prev < 0:
+		case a.SortKey <= prev:
+			addDiagnostic(code, line, "warning", "article %s follows article %s", a.Number, prevNumber)
+		case base > prev/1000+1:
+			addDiagnostic(code, line, "warning", "gap in numbering: article %s follows article %s", a.Number, prevNumber)
+		}
+		prev = a.SortKey
+		prevNumber = a.Number
+	}
+	sort.SliceStable(code.Diagnostics, func(i, j int) bool { return code.Diagnostics[i].Line < code.Diagnostics[j].Line })
+}
+
+// getCodeDiagnosticsHandler returns the diagnostics of the source text of a
+// code, so editors can check a text after fixing it. An optional severity
+// parameter keeps only the diagnostics of that severity.
+func getCodeDiagnosticsHandler(c *gin.Context) {
+	id := c.Param("id")
+	if _, ok := codeFiles[id]; !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "code not found"})
+		return
+	}
+	diagnostics, err := diagnosticsFor(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to parse code"})
+		return
+	}
+	out := []Diagnostic{}
+	counts := map[string]int{}
+	for _, d := range diagnostics {
+		counts[d.Severity]++
+		if s := c.Query("severity"); s == "" || s == d.Severity {
+			out = append(out, d)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"code": id, "counts": counts, "diagnostics": out})
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseDiagnostics(t *testing.T) {
+	g, err := loadGrammar("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := []string{
+		"Notă",
+		"Text publicat în Monitorul Oficial.",
+		"Articolul 1",
+		"(1) Legea penală prevede faptele care constituie infracțiuni.",
+		"Articolul 2",
+		"(1) " + strings.Repeat("text ", maxLineLength/5+1),
+		"Articolul 2",
+		"(1) Al doilea articol 2.",
+		"Articolul 1",
+		"(1) Un articol 1 după articolul 2.",
+		"Articolul 7",
+		"(1) Urmează după o lacună.",
+	}
+	pc := parseCodeLines(lines, "penal", "Codul Penal", g)
+	type diag struct {
+		line     int
+		severity string
+		message  string
+	}
+	want := []diag{
+		{1, "warning", "note outside any article ignored"},
+		{3, "warning", `no book heading before this line, added placeholder book "Intro"`},
+		{3, "warning", `no title heading before this line, added placeholder title "Untitled"`},
+		{3, "warning", `no chapter heading before this line, added placeholder chapter "Unnamed"`},
+		{6, "info", "very long line"},
+		{7, "warning", "duplicate article number 2, first seen at line 5"},
+		{7, "warning", "article 2 follows article 2"},
+		{9, "warning", "duplicate article number 1, first seen at line 3"},
+		{9, "warning", "article 1 follows article 2"},
+		{11, "warning", "gap in numbering: article 7 follows article 1"},
+	}
+	if len(pc.Diagnostics) != len(want) {
+		t.Fatalf("got %d diagnostics, want %d: %+v", len(pc.Diagnostics), len(want), pc.Diagnostics)
+	}
+	for i, w := range want {
+		d := pc.Diagnostics[i]
+		if d.Line != w.line || d.Severity != w.severity || !strings.HasPrefix(d.Message, w.message) {
+			t.Errorf("diagnostic %d: %d %s %q, want %d %s %q", i, d.Line, d.Severity, d.Message, w.line, w.severity, w.message)
+		}
+	}
+}
+
+// The diagnostics of the startup parsing are served while the text is
+// unchanged; a modified text is parsed again.
+func TestCodeDiagnosticsHandler(t *testing.T) {
+	useTestCode(t, testCodeText)
+	t.Cleanup(func() {
+		diagnosticsMu.Lock()
+		delete(codeDiagnostics, "test")
+		diagnosticsMu.Unlock()
+	})
+	source, err := statSource(codeFiles["test"].path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	storeDiagnostics("test", source, []Diagnostic{{Line: 1, Severity: "info", Message: "stored"}, {Line: 2, Severity: "warning", Message: "stored"}})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/code-diagnostics/:id", getCodeDiagnosticsHandler)
+	get := func(path string) (int, []Diagnostic, map[string]int) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		var out struct {
+			Counts      map[string]int `json:"counts"`
+			Diagnostics []Diagnostic   `json:"diagnostics"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &out)
+		return w.Code, out.Diagnostics, out.Counts
+	}
+
+	status, diags, counts := get("/code-diagnostics/test?severity=warning")
+	if status != http.StatusOK || len(diags) != 1 || diags[0].Message != "stored" || counts["info"] != 1 || counts["warning"] != 1 {
+		t.Errorf("stored diagnostics: status %d, %+v, counts %v", status, diags, counts)
+	}
+
+	text := testCodeText + "\nArticolul 1\n\n(1) Articol duplicat.\n"
+	if err := os.WriteFile(codeFiles["test"].path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	status, diags, _ = get("/code-diagnostics/test?severity=warning")
+	found := false
+	for _, d := range diags {
+		if strings.HasPrefix(d.Message, "duplicate article number 1") {
+			found = true
+		}
+		if d.Message == "stored" {
+			t.Errorf("stale diagnostic served after the text changed")
+		}
+	}
+	if status != http.StatusOK || !found {
+		t.Errorf("after the change: status %d, %+v", status, diags)
+	}
+
+	if status, _, _ = get("/code-diagnostics/nope"); status != http.StatusNotFound {
+		t.Errorf("unknown code: status %d", status)
+	}
+}
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DiffOp is a run of words of a text that is unchanged ("equal"), only in the
+// new text ("insert") or only in the old one ("delete"). Runs are joined with
+// a space, except around line breaks.
+type DiffOp struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// ArticleDiff describes how an article changed between two versions of a
+// code. Content and Notes are only set when they changed; for added and
+// removed articles they hold the whole text.
+type ArticleDiff struct {
+	Number   string   `json:"number"`
+	ID       string   `json:"id"`
+	Status   string   `json:"status"` // "added", "removed" or "modified"
+	Title    string   `json:"title"`
+	OldTitle string   `json:"oldTitle,omitempty"`
+	Repealed bool     `json:"repealed,omitempty"`
+	Content  []DiffOp `json:"content,omitempty"`
+	Notes    []DiffOp `json:"notes,omitempty"`
+}
+
+// CodeDiff lists the articles that changed between two versions of a code,
+// in the order of their numbers.
+type CodeDiff struct {
+	Code     string        `json:"code"`
+	From     string        `json:"from"`
+	To       string        `json:"to"`
+	Added    int           `json:"added"`
+	Removed  int           `json:"removed"`
+	Modified int           `json:"modified"`
+	Articles []ArticleDiff `json:"articles"`
+}
+
+// diffParsedCodes compares two versions of a code. Articles are matched by
+// number; when a number occurs several times (laws reproduced inside a code)
+// the occurrences are matched in document order.
+func diffParsedCodes(from, to *ParsedCode) *CodeDiff {
+	d := &CodeDiff{Code: to.ID, Articles: []ArticleDiff{}}
+	old := map[string][]*Article{}
+	for i := range from.Articles {
+		a := &from.Articles[i]
+		old[a.Number] = append(old[a.Number], a)
+	}
+	seen := map[string]int{}
+	for i := range to.Articles {
+		a := &to.Articles[i]
+		n := seen[a.Number]
+		seen[a.Number]++
+		if n >= len(old[a.Number]) {
+			d.Added++
+			d.Articles = append(d.Articles, ArticleDiff{
+				Number: a.Number, ID: a.ID, Status: "added", Title: a.Title, Repealed: a.Repealed,
+				Content: wordDiff("", a.Content), Notes: wordDiff("", strings.Join(a.Notes, "\n")),
+			})
+			continue
+		}
+		if ad, changed := diffArticle(old[a.Number][n], a); changed {
+			d.Modified++
+			d.Articles = append(d.Articles, ad)
+		}
+	}
+	occurrence := map[string]int{}
+	for i := range from.Articles {
+		a := &from.Articles[i]
+		occurrence[a.Number]++
+		if occurrence[a.Number] <= seen[a.Number] {
+			continue
+		}
+		d.Removed++
+		d.Articles = append(d.Articles, ArticleDiff{
+			Number: a.Number, ID: a.ID, Status: "removed", Title: a.Title,
+			Content: wordDiff(a.Content, ""), Notes: wordDiff(strings.Join(a.Notes, "\n"), ""),
+		})
+	}
+	sort.SliceStable(d.Articles, func(i, j int) bool {
+		return articleSortKey(d.Articles[i].Number) < articleSortKey(d.Articles[j].Number)
+	})
+	return d
+}
+
+// diffArticle compares two versions of an article and reports whether its
+// title, content or notes changed.
+func diffArticle(from, to *Article) (ArticleDiff, bool) {
+	ad := ArticleDiff{Number: to.Number, ID: to.ID, Status: "modified", Title: to.Title, Repealed: to.Repealed}
+	changed := false
+	if from.Title != to.Title {
+		ad.OldTitle = from.Title
+		changed = true
+	}
+	if from.Content != to.Content {
+		ad.Content = wordDiff(from.Content, to.Content)
+		changed = true
+	}
+	if oldNotes, newNotes := strings.Join(from.Notes, "\n"), strings.Join(to.Notes, "\n"); oldNotes != newNotes {
+		ad.Notes = wordDiff(oldNotes, newNotes)
+		changed = true
+	}
+	return ad, changed
+}
+
+var diffTokenRe = regexp.MustCompile(`\S+|\n`)
+
+// above this many word pairs the changed part of a text is reported as
+// deleted and inserted whole instead of being compared word by word
+const maxDiffCells = 4000000
+
+// wordDiff compares two texts word by word. Line breaks count as words so
+// that paragraphs stay apart.
+func wordDiff(a, b string) []DiffOp {
+	x, y := diffTokenRe.FindAllString(a, -1), diffTokenRe.FindAllString(b, -1)
+	var ops []DiffOp
+	add := func(op string, words []string) {
+		if len(words) == 0 {
+			return
+		}
+		if n := len(ops); n > 0 && ops[n-1].Op == op {
+			ops[n-1].Text = joinWords([]string{ops[n-1].Text}, words)
+			return
+		}
+		ops = append(ops, DiffOp{Op: op, Text: joinWords(nil, words)})
+	}
+
+	// most changes touch a few words, so compare only what lies between
+	// the common prefix and suffix
+	p := 0
+	for p < len(x) && p < len(y) && x[p] == y[p] {
+		p++
+	}
+	s := 0
+	for s < len(x)-p && s < len(y)-p && x[len(x)-1-s] == y[len(y)-1-s] {
+		s++
+	}
+	add("equal", x[:p])
+	mx, my := x[p:len(x)-s], y[p:len(y)-s]
+	if len(mx)*len(my) > maxDiffCells {
+		add("delete", mx)
+		add("insert", my)
+	} else {
+		// lcs[i*(m+1)+j] is the length of the longest common subsequence
+		// of mx[i:] and my[j:]
+		n, m := len(mx), len(my)
+		lcs := make([]int32, (n+1)*(m+1))
+		for i := n - 1; i >= 0; i-- {
+			for j := m - 1; j >= 0; j-- {
+				if mx[i] == my[j] {
+					lcs[i*(m+1)+j] = lcs[(i+1)*(m+1)+j+1] + 1
+				} else if l, r := lcs[(i+1)*(m+1)+j], lcs[i*(m+1)+j+1]; l >= r {
+					lcs[i*(m+1)+j] = l
+				} else {
+					lcs[i*(m+1)+j] = r
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < n || j < m {
+			switch {
+			case i < n && j < m && mx[i] == my[j]:
+				add("equal", mx[i:i+1])
+				i, j = i+1, j+1
+			case j == m || (i < n && lcs[(i+1)*(m+1)+j] >= lcs[i*(m+1)+j+1]):
+				add("delete", mx[i:i+1])
+				i++
+			default:
+				add("insert", my[j:j+1])
+				j++
+			}
+		}
+	}
+	add("equal", x[len(x)-s:])
+	return ops
+}
+
+// joinWords appends words to the text parts with single spaces between them,
+// but none around line breaks.
+func joinWords(parts []string, words []string) string {
+	var b strings.Builder
+	prev := ""
+	for _, w := range append(parts, words...) {
+		if b.Len() > 0 && w != "\n" && !strings.HasSuffix(prev, "\n") {
+			b.WriteString(" ")
+		}
+		b.WriteString(w)
+		prev = w
+	}
+	return b.String()
+}
+
+// getCodeDiffHandler compares two versions of a code. from and to are dates
+// (YYYY-MM-DD) selecting the versions in force on those days; to defaults to
+// today and from to the version preceding to.
+func getCodeDiffHandler(c *gin.Context) {
+	id := c.Param("id")
+	versions, err := codeVersions(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "code not found"})
+		return
+	}
+	to := c.DefaultQuery("to", time.Now().Format("2006-01-02"))
+	from := c.Query("from")
+	for _, d := range []string{from, to} {
+		if _, err := time.Parse("2006-01-02", d); d != "" && err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be YYYY-MM-DD"})
+			return
+		}
+	}
+	toCode, toVersion, err := loadParsedCodeAt(id, to)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if from == "" {
+		for _, v := range versions {
+			if v.Date < toVersion.Date {
+				from = v.Date
+			}
+		}
+		if from == "" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "no earlier version of the code"})
+			return
+		}
+	}
+	fromCode, fromVersion, err := loadParsedCodeAt(id, from)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	d := diffParsedCodes(fromCode, toCode)
+	d.From, d.To = fromVersion.Date, toVersion.Date
+	c.JSON(http.StatusOK, d)
+}
+
+// runDiffCommand implements "go run . diff [-json] <code> <from> [<to>]",
+// which prints the articles that changed between two texts of a code. from
+// and to are dates selecting dated versions, as in the API, or paths to code
+// texts, such as a new consolidated text not yet copied into
+// codurileactualizate; to defaults to the current text.
+func runDiffCommand(args []string) int {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the diff as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go run . diff [-json] <code> <from> [<to>]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() < 2 || fs.NArg() > 3 {
+		fs.Usage()
+		return 2
+	}
+	loadCodeRegistry()
+	id := fs.Arg(0)
+	info, ok := codeFiles[id]
+	if !ok {
+		fmt.Fprintln(os.Stderr, "unknown code:", id)
+		return 1
+	}
+	load := func(spec string) (*ParsedCode, string, error) {
+		path := spec
+		if versionDateRe.MatchString(spec) {
+			v, err := versionAt(id, spec)
+			if err != nil {
+				return nil, "", err
+			}
+			path, spec = v.path, v.Date
+		}
+		if path == info.path {
+			spec = inForceSince(path)
+		}
+		pc, err := buildParsedCode(path, id, info.title)
+		return pc, spec, err
+	}
+	fromCode, from, err := load(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	toSpec := info.path
+	if fs.NArg() == 3 {
+		toSpec = fs.Arg(2)
+	}
+	toCode, to, err := load(toSpec)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	d := diffParsedCodes(fromCode, toCode)
+	d.From, d.To = from, to
+
+	if *asJSON {
+		data, _ := json.MarshalIndent(d, "", "  ")
+		fmt.Println(string(data))
+		return 0
+	}
+	fmt.Printf("%s: %s -> %s: %d added, %d removed, %d modified\n", id, d.From, d.To, d.Added, d.Removed, d.Modified)
+	marks := map[string]string{"added": "+", "removed": "-", "modified": "~"}
+	for _, a := range d.Articles {
+		fmt.Printf("\n%s Articolul %s %s\n", marks[a.Status], a.Number, a.Title)
+		if a.OldTitle != "" {
+			fmt.Printf("  title: [-%s-]{+%s+}\n", a.OldTitle, a.Title)
+		}
+		if a.Status != "modified" {
+			continue
+		}
+		for _, part := range []struct {
+			name string
+			ops  []DiffOp
+		}{{"text", a.Content}, {"notes", a.Notes}} {
+			if part.ops != nil {
+				fmt.Printf("  %s: %s\n", part.name, strings.ReplaceAll(plainWordDiff(part.ops), "\n", "\n    "))
+			}
+		}
+	}
+	return 0
+}
+
+// plainWordDiff renders a word diff the way git diff --word-diff=plain does.
+func plainWordDiff(ops []DiffOp) string {
+	words := make([]string, 0, len(ops))
+	for _, op := range ops {
+		switch op.Op {
+		case "insert":
+			words = append(words, "{+"+op.Text+"+}")
+		case "delete":
+			words = append(words, "[-"+op.Text+"-]")
+		default:
+			words = append(words, op.Text)
+		}
+	}
+	return joinWords(nil, words)
+}
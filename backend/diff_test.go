@@ -0,0 +1,90 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWordDiff(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want []DiffOp
+	}{
+		{"a b c", "a b c", []DiffOp{{"equal", "a b c"}}},
+		{"", "a b", []DiffOp{{"insert", "a b"}}},
+		{"a b", "", []DiffOp{{"delete", "a b"}}},
+		{"", "", nil},
+		{"legea penală prevede faptele", "numai legea penală prevede faptele", []DiffOp{{"insert", "numai"}, {"equal", "legea penală prevede faptele"}}},
+		{"pedeapsa este de 2 ani", "pedeapsa este de 3 ani", []DiffOp{{"equal", "pedeapsa este de"}, {"delete", "2"}, {"insert", "3"}, {"equal", "ani"}}},
+		{"a x b y c", "a b c", []DiffOp{{"equal", "a"}, {"delete", "x"}, {"equal", "b"}, {"delete", "y"}, {"equal", "c"}}},
+		// line breaks are words and are joined without spaces
+		{"(1) a b\n(2) c", "(1) a b\n(2) d\n(3) e", []DiffOp{{"equal", "(1) a b\n(2)"}, {"delete", "c"}, {"insert", "d\n(3) e"}}},
+	}
+	for _, tt := range tests {
+		if got := wordDiff(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("wordDiff(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// Above maxDiffCells the changed part is reported whole; the common prefix
+// and suffix are still kept.
+func TestWordDiffLarge(t *testing.T) {
+	a := "start " + strings.Repeat("x ", 2100) + "end"
+	b := "start " + strings.Repeat("y ", 2100) + "end"
+	got := wordDiff(a, b)
+	if len(got) != 4 || got[0] != (DiffOp{"equal", "start"}) || got[1].Op != "delete" || got[2].Op != "insert" || got[3] != (DiffOp{"equal", "end"}) {
+		t.Errorf("wordDiff of long texts: %d ops, first %+v", len(got), got[:1])
+	}
+}
+
+func TestDiffParsedCodes(t *testing.T) {
+	article := func(number, title, content string, notes ...string) Article {
+		return Article{ID: "test/art-" + number, Number: number, Title: title, Content: content, Notes: notes}
+	}
+	from := &ParsedCode{ID: "test", Articles: []Article{
+		article("1", "Legalitatea incriminării", "(1) Legea penală prevede faptele."),
+		article("2", "Legalitatea sancțiunilor", "(1) Legea penală prevede pedepsele."),
+		article("3", "Aplicarea legii", "(1) Legea penală se aplică."),
+		article("1", "Intrarea în vigoare", "Prezenta lege intră în vigoare."),
+		article("10", "Titlul", "Text neschimbat.", "Nota veche."),
+	}}
+	to := &ParsedCode{ID: "test", Articles: []Article{
+		article("1", "Legalitatea incriminării", "(1) Numai legea penală prevede faptele."),
+		article("3", "Aplicarea legii penale", "(1) Legea penală se aplică."),
+		article("4", "Articol nou", "(1) Text nou."),
+		article("10", "Titlul", "Text neschimbat.", "Nota nouă."),
+	}}
+	d := diffParsedCodes(from, to)
+	if d.Code != "test" || d.Added != 1 || d.Removed != 2 || d.Modified != 3 {
+		t.Errorf("added %d, removed %d, modified %d, want 1, 2 and 3", d.Added, d.Removed, d.Modified)
+	}
+	var got []string
+	for _, a := range d.Articles {
+		got = append(got, a.Status+" "+a.Number)
+	}
+	// the second article 1 has no counterpart; articles are in number order
+	want := []string{"modified 1", "removed 1", "removed 2", "modified 3", "added 4", "modified 10"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("articles %q, want %q", got, want)
+	}
+	if a := d.Articles[0]; a.OldTitle != "" || !reflect.DeepEqual(a.Content, []DiffOp{{"equal", "(1)"}, {"delete", "Legea"}, {"insert", "Numai legea"}, {"equal", "penală prevede faptele."}}) || a.Notes != nil {
+		t.Errorf("article 1: %+v", a)
+	}
+	if a := d.Articles[1]; a.Title != "Intrarea în vigoare" || !reflect.DeepEqual(a.Content, []DiffOp{{"delete", "Prezenta lege intră în vigoare."}}) {
+		t.Errorf("removed article 1: %+v", a)
+	}
+	if a := d.Articles[3]; a.OldTitle != "Aplicarea legii" || a.Title != "Aplicarea legii penale" || a.Content != nil {
+		t.Errorf("article 3: %+v", a)
+	}
+	if a := d.Articles[4]; !reflect.DeepEqual(a.Content, []DiffOp{{"insert", "(1) Text nou."}}) || a.Notes != nil {
+		t.Errorf("article 4: %+v", a)
+	}
+	if a := d.Articles[5]; a.Content != nil || !reflect.DeepEqual(a.Notes, []DiffOp{{"equal", "Nota"}, {"delete", "veche."}, {"insert", "nouă."}}) {
+		t.Errorf("article 10: %+v", a)
+	}
+	if d := diffParsedCodes(to, to); len(d.Articles) != 0 || d.Added+d.Removed+d.Modified != 0 {
+		t.Errorf("diff of a code with itself: %+v", d)
+	}
+}
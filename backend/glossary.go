@@ -0,0 +1,235 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// GlossaryEntry is a term defined by an article of a code, such as "Prin
+// teritoriul României se înțelege ..." or "Moneda virtuală înseamnă ...".
+// Scope keeps the limit the article puts on the definition ("în sensul
+// prezentului titlu"), if any.
+type GlossaryEntry struct {
+	Term       string `json:"term"`
+	Definition string `json:"definition"`
+	Scope      string `json:"scope,omitempty"`
+	Code       string `json:"code"`
+	Article    string `json:"article"`
+	ArticleID  string `json:"articleId"`
+	Paragraph  string `json:"paragraph,omitempty"`
+}
+
+var (
+	// "Prin X se înțelege Y", "În sensul prezentului cod, prin X se
+	// înțelege Y", "Prin X, în sensul prezentului articol, se înțelege Y"
+	prinDefinitionRe = regexp.MustCompile(`(?i)(?:^|[.;:]\s+|,\s*)prin\s+(?:sintagma\s+|termenul\s+|expresia\s+|no[țţt]iunea\s+(?:de\s+)?)?(.{2,150}?)\s*,?\s+(?:((?:[ÎIîi]n\s+sensul|conform|potrivit)\s+[^,]{3,80}),\s*)?se\s+[îi]n[țţt]elege\s*(.*)$`)
+	// "Moneda virtuală înseamnă Y", "În sensul prezentei legi, X înseamnă Y"
+	meansDefinitionRe = regexp.MustCompile(`(?i)^(?:([ÎIîi]n\s+sensul\s+[^,]{3,80}),\s*)?(\p{L}.{1,100}?)\s+[îi]nseamn[ăa]\s+(.+)$`)
+	// "Funcționar public, în sensul legii penale, este Y", "Arme sunt Y"
+	isDefinitionRe = regexp.MustCompile(`^(\p{Lu}[^,.;:()]{1,80}?)(?:,\s+([ÎIîi]n\s+sensul\s+[^,]{3,80}),)?\s+(?:este|sunt)\s+(.+)$`)
+	sensulPrefixRe = regexp.MustCompile(`(?i)^([ÎIîi]n\s+sensul\s+[^,]{3,80}),`)
+)
+
+// articles with these titles define the subject of their first sentence
+var definingTitles = []string{"notiune", "notiunea", "notiuni", "definitie", "definitia", "definitii", "intelesul"}
+
+// longer "terms" are sentences that happen to contain "prin"
+const maxTermWords = 10
+
+// findDefinitions returns the definitions given by a paragraph or letter of
+// the article titled title. A definition ending in a colon continues with the
+// letters of the paragraph. "X este Y" only counts as a definition when it is
+// limited to a scope ("în sensul legii penale") or when the title announces
+// it (see definesTerm), and Y reads as a noun phrase.
+func findDefinitions(text string, letters []Letter, title string) []GlossaryEntry {
+	var out []GlossaryEntry
+	// later lines of a paragraph are usually decisions quoted in the text
+	text, _, _ = strings.Cut(text, "\n")
+	scope := ""
+	if m := sensulPrefixRe.FindStringSubmatch(text); m != nil {
+		scope = m[1]
+	}
+	var term, def string
+	if m := prinDefinitionRe.FindStringSubmatch(text); m != nil {
+		term, def = m[1], m[3]
+		if m[2] != "" {
+			scope = m[2]
+		}
+	} else if m := meansDefinitionRe.FindStringSubmatch(text); m != nil {
+		if m[1] != "" {
+			scope = m[1]
+		}
+		term, def = m[2], m[3]
+	} else if m := isDefinitionRe.FindStringSubmatch(text); m != nil && (m[2] != "" || definesTerm(title, m[1])) && nounPhrase(m[3]) {
+		term, def = m[1], m[3]
+		if m[2] != "" {
+			scope = m[2]
+		}
+	}
+	term = strings.Trim(term, " ,\"'„“”«»")
+	if term == "" || len(strings.Fields(term)) > maxTermWords || strings.ContainsAny(term, "0123456789()") {
+		return nil
+	}
+	def = strings.TrimLeft(def, " :–-")
+	if strings.HasSuffix(def, ":") || def == "" {
+		var items []string
+		for _, l := range letters {
+			items = append(items, l.Letter+") "+l.Text)
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		def = strings.TrimSpace(def + "\n" + strings.Join(items, "\n"))
+	}
+	out = append(out, GlossaryEntry{Term: term, Definition: def, Scope: strings.TrimSpace(scope)})
+	return out
+}
+
+// definesTerm reports whether an article title announces a definition
+// ("Noțiune", "Înțelesul unor termeni") or is the term itself ("Arme").
+func definesTerm(title, term string) bool {
+	words := strings.Fields(foldWord(title))
+	if len(words) == 0 || strings.HasSuffix(term, " nu") {
+		return false
+	}
+	for _, t := range definingTitles {
+		if words[0] == t {
+			return true
+		}
+	}
+	return strings.Join(words, " ") == foldWord(term)
+}
+
+// nounPhrase reports whether text starts like a noun phrase ("legătura
+// dintre...", "o reprezentare...", "acela care..."), which tells "Afinitatea
+// este legătura..." apart from "Privilegiul este indivizibil". Feminine
+// adjectives end in "ă" while the articled nouns end in "a".
+func nounPhrase(text string) bool {
+	first := strings.ToLower(strings.Trim(strings.SplitN(text, " ", 2)[0], ",.;:"))
+	switch first {
+	case "o", "un", "orice", "acea", "acel", "acela", "aceea", "acele", "acei", "aceia":
+		return true
+	}
+	for _, suffix := range []string{"ul", "a", "ii", "ele"} {
+		if strings.HasSuffix(first, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// extractGlossary collects the definitions given in the articles of a code.
+// Notes are left out: the definitions quoted there come from court decisions.
+func extractGlossary(code *ParsedCode) []GlossaryEntry {
+	var out []GlossaryEntry
+	for _, a := range code.Articles {
+		add := func(entries []GlossaryEntry, paragraph string) {
+			for _, e := range entries {
+				e.Code, e.Article, e.ArticleID, e.Paragraph = code.ID, a.Number, a.ID, paragraph
+				out = append(out, e)
+			}
+		}
+		for _, p := range a.Paragraphs {
+			add(findDefinitions(p.Text, p.Letters, a.Title), p.Number)
+			for _, l := range p.Letters {
+				add(findDefinitions(l.Text, nil, a.Title), p.Number)
+			}
+		}
+	}
+	return out
+}
+
+// the glossary spans all codes, so like the citation graph it is built on
+// first use and dropped whenever a parsed code changes
+var (
+	glossaryMu    sync.Mutex
+	glossaryBuilt bool
+	glossary      []GlossaryEntry
+)
+
+func invalidateGlossary() {
+	glossaryMu.Lock()
+	glossaryBuilt = false
+	glossary = nil
+	glossaryMu.Unlock()
+}
+
+func glossaryEntries() []GlossaryEntry {
+	glossaryMu.Lock()
+	defer glossaryMu.Unlock()
+	if !glossaryBuilt {
+		ids := make([]string, 0, len(codeFiles))
+		for id := range codeFiles {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		var all []GlossaryEntry
+		for _, id := range ids {
+			pc, err := loadParsedCode(id)
+			if err != nil {
+				continue
+			}
+			all = append(all, extractGlossary(pc)...)
+		}
+		glossary, glossaryBuilt = all, true
+	}
+	return glossary
+}
+
+// glossaryHandler lists the defined terms. q searches the terms, diacritics
+// and case ignored: exact matches come first, then terms starting with q,
+// then terms containing it, then entries whose definition mentions it.
+// code restricts the list to one code and limit caps its length.
+func glossaryHandler(c *gin.Context) {
+	code := c.Query("code")
+	if _, ok := codeFiles[code]; code != "" && !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "code not found"})
+		return
+	}
+	q := foldWord(strings.TrimSpace(c.Query("q")))
+	type ranked struct {
+		GlossaryEntry
+		rank int
+	}
+	var found []ranked
+	for _, e := range glossaryEntries() {
+		if code != "" && e.Code != code {
+			continue
+		}
+		term := foldWord(e.Term)
+		rank := 0
+		switch {
+		case q == "":
+		case term == q:
+		case strings.HasPrefix(term, q):
+			rank = 1
+		case strings.Contains(term, q):
+			rank = 2
+		case strings.Contains(foldWord(e.Definition), q):
+			rank = 3
+		default:
+			continue
+		}
+		found = append(found, ranked{e, rank})
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].rank != found[j].rank {
+			return found[i].rank < found[j].rank
+		}
+		return foldWord(found[i].Term) < foldWord(found[j].Term)
+	})
+	out := make([]GlossaryEntry, 0, len(found))
+	for _, f := range found {
+		out = append(out, f.GlossaryEntry)
+	}
+	total := len(out)
+	if n, err := strconv.Atoi(c.Query("limit")); err == nil && n >= 0 && n < len(out) {
+		out = out[:n]
+	}
+	c.JSON(http.StatusOK, gin.H{"total": total, "entries": out})
+}
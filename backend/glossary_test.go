@@ -0,0 +1,44 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindDefinitions(t *testing.T) {
+	tests := []struct {
+		text, title string
+		letters     []Letter
+		want        []GlossaryEntry
+	}{
+		{"Prin teritoriul României se înțelege întinderea de pământ, marea teritorială și apele cu solul, subsolul și spațiul aerian, cuprinse între frontierele de stat.", "Teritoriul",
+			nil, []GlossaryEntry{{Term: "teritoriul României", Definition: "întinderea de pământ, marea teritorială și apele cu solul, subsolul și spațiul aerian, cuprinse între frontierele de stat."}}},
+		{"În sensul legii penale, prin funcționar public se înțelege persoana care exercită atribuții publice.", "Funcționar public",
+			nil, []GlossaryEntry{{Term: "funcționar public", Definition: "persoana care exercită atribuții publice.", Scope: "În sensul legii penale"}}},
+		{"Prin sintagma „membri de familie”, în sensul prezentului articol, se înțelege soțul și rudele apropiate.", "Membru de familie",
+			nil, []GlossaryEntry{{Term: "membri de familie", Definition: "soțul și rudele apropiate.", Scope: "în sensul prezentului articol"}}},
+		{"În sensul legii penale, moneda virtuală înseamnă o reprezentare digitală a valorii.", "Moneda virtuală",
+			nil, []GlossaryEntry{{Term: "moneda virtuală", Definition: "o reprezentare digitală a valorii.", Scope: "În sensul legii penale"}}},
+		// the definition goes on with the letters of the paragraph
+		{"În sensul prezentului cod, prin consumator se înțelege:", "Definiții",
+			[]Letter{{"a", "persoana fizică;"}, {"b", "asociația de proprietari."}},
+			[]GlossaryEntry{{Term: "consumator", Definition: "a) persoana fizică;\nb) asociația de proprietari.", Scope: "În sensul prezentului cod"}}},
+		{"Prin rude apropiate se înțelege:", "Rude", nil, nil},
+		// "X este Y" needs a scope or a defining title, and a noun phrase
+		{"Afinitatea este legătura dintre un soț și rudele celuilalt soț.", "Noțiune",
+			nil, []GlossaryEntry{{Term: "Afinitatea", Definition: "legătura dintre un soț și rudele celuilalt soț."}}},
+		{"Arme sunt instrumentele, piesele sau dispozitivele astfel declarate prin dispoziții legale.", "Arme",
+			nil, []GlossaryEntry{{Term: "Arme", Definition: "instrumentele, piesele sau dispozitivele astfel declarate prin dispoziții legale."}}},
+		{"Privilegiul este indivizibil.", "Noțiune", nil, nil},
+		{"Pedeapsa este închisoarea de la 2 la 7 ani.", "Furtul calificat", nil, nil},
+		// sentences that happen to contain "prin"
+		{"Fapta săvârșită prin violență se pedepsește cu închisoare de la 1 la 5 ani, dacă prin aceasta se înțelege să se obțină un folos.", "Tâlhăria", nil, nil},
+		// later lines of a paragraph quote decisions
+		{"Legea penală prevede faptele.\nPrin decizia nr. 1 se înțelege ceva.", "Legalitatea incriminării", nil, nil},
+	}
+	for _, tt := range tests {
+		if got := findDefinitions(tt.text, tt.letters, tt.title); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("findDefinitions(%q) = %+v, want %+v", tt.text, got, tt.want)
+		}
+	}
+}
@@ -5,8 +5,10 @@ go 1.21
 require github.com/gin-gonic/gin v1.10.1
 
 require (
+	github.com/andybalholm/brotli v1.1.0
 	github.com/google/uuid v1.6.0
 	github.com/gorilla/websocket v1.5.0
+	golang.org/x/net v0.25.0
 )
 
 require (
@@ -31,7 +33,6 @@ require (
 	github.com/ugorji/go/codec v1.2.12 // indirect
 	golang.org/x/arch v0.8.0 // indirect
 	golang.org/x/crypto v0.23.0 // indirect
-	golang.org/x/net v0.25.0 // indirect
 	golang.org/x/sys v0.20.0 // indirect
 	golang.org/x/text v0.15.0 // indirect
 	google.golang.org/protobuf v1.34.1 // indirect
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Grammar describes how the headings, articles and notes of a legal act are
+// written, so acts laid out differently from the codes can be parsed without
+// code changes. Grammars other than the built-in default are read from
+// codurileactualizate/grammars/<name>.json.
+//
+// Levels maps heading patterns to the levels of the parsed tree (part, book,
+// title, chapter, section, subsection); levels an act does not use are simply
+// left out. The article pattern must capture the article number in a group
+// named "number" ("{number}" expands to the usual article number forms) and
+// may capture a "title" or "content" group for text on the heading line.
+type Grammar struct {
+	Name       string         `json:"name"`
+	Levels     []GrammarLevel `json:"levels"`
+	Article    string         `json:"article"`
+	Notes      []string       `json:"notes"`
+	References string         `json:"references"`
+}
+
+type GrammarLevel struct {
+	Level   string `json:"level"`
+	Pattern string `json:"pattern"`
+}
+
+// defaultGrammar is the layout of the four codes.
+var defaultGrammar = Grammar{
+	Name: "cod",
+	Levels: []GrammarLevel{
+		{Level: "part", Pattern: `^Partea\s+(?:\p{Lu}{2,}|[IVX]+|a\s+[IVX]+-a)(?:\s|$)`},
+		{Level: "book", Pattern: `(?i)^Cartea`},
+		// "Titlul executoriu" is also the title of some articles
+		{Level: "title", Pattern: `(?i)^Titlul\s+(?:[IVXLC]+(?:\^\d+)?|PRELIMINAR|UNIC)(?:\s|$)`},
+		{Level: "chapter", Pattern: `(?i)^Capitolul`},
+		{Level: "section", Pattern: `(?i)^Sec[tțţ]iunea`},
+		{Level: "subsection", Pattern: `(?i)^Subsec[tțţ]iunea`},
+	},
+	// "Articolul 86^1 - Titlu", "Articolul 2.663", "Articolul 281 bis"; the
+	// abbreviated "Art. 86^1" is only a heading when it stands on its own line
+	Article:    `(?i)^(?:Articolul\s+(?P<number>{number})\.?\s*(?:-\s*(?P<title>.+))?|Art\.\s+(?P<number>{number})\.?)$`,
+	Notes:      []string{`(?i)^Not[aă]`},
+	References: `(?i)(monitorul oficial|legea nr|ril nr|decizia)`,
+}
+
+var grammarLevels = []string{"part", "book", "title", "chapter", "section", "subsection"}
+
+// neverRe stands in for the levels a grammar does not use.
+var neverRe = regexp.MustCompile(`[^\x00-\x{10FFFF}]`)
+
+// compiledGrammar holds the regular expressions of a grammar.
+type compiledGrammar struct {
+	name       string
+	levels     map[string]*regexp.Regexp
+	article    *regexp.Regexp
+	note       *regexp.Regexp
+	references *regexp.Regexp
+}
+
+func compileGrammar(g Grammar) (*compiledGrammar, error) {
+	cg := &compiledGrammar{name: g.Name, levels: map[string]*regexp.Regexp{}, note: neverRe, references: neverRe}
+	for _, l := range grammarLevels {
+		cg.levels[l] = neverRe
+	}
+	for _, l := range g.Levels {
+		if _, ok := cg.levels[l.Level]; !ok {
+			return nil, fmt.Errorf("grammar %s: unknown level %q", g.Name, l.Level)
+		}
+		re, err := regexp.Compile(l.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("grammar %s: level %s: %v", g.Name, l.Level, err)
+		}
+		cg.levels[l.Level] = re
+	}
+	re, err := regexp.Compile(strings.ReplaceAll(g.Article, "{number}", articleNumberPattern))
+	if err != nil {
+		return nil, fmt.Errorf("grammar %s: article: %v", g.Name, err)
+	}
+	if re.SubexpIndex("number") < 0 {
+		return nil, fmt.Errorf("grammar %s: article pattern has no \"number\" group", g.Name)
+	}
+	cg.article = re
+	if len(g.Notes) > 0 {
+		if cg.note, err = regexp.Compile("(?:" + strings.Join(g.Notes, ")|(?:") + ")"); err != nil {
+			return nil, fmt.Errorf("grammar %s: notes: %v", g.Name, err)
+		}
+	}
+	if g.References != "" {
+		if cg.references, err = regexp.Compile(g.References); err != nil {
+			return nil, fmt.Errorf("grammar %s: references: %v", g.Name, err)
+		}
+	}
+	return cg, nil
+}
+
+// uses reports whether the grammar has headings for level.
+func (g *compiledGrammar) uses(level string) bool {
+	return g.levels[level] != neverRe
+}
+
+// isHeading reports whether the line opens any level or an article.
+func (g *compiledGrammar) isHeading(line string) bool {
+	for _, re := range g.levels {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return g.article.MatchString(line)
+}
+
+// articleHeading returns the number, title and inline content of an article
+// heading. Every group named "number" is consulted, since alternatives of the
+// pattern may capture the number in different places.
+func (g *compiledGrammar) articleHeading(line string) (number, title, content string) {
+	m := g.article.FindStringSubmatch(line)
+	if m == nil {
+		return "", "", ""
+	}
+	for i, name := range g.article.SubexpNames() {
+		switch name {
+		case "number":
+			number += m[i]
+		case "title":
+			title += m[i]
+		case "content":
+			content += m[i]
+		}
+	}
+	return number, strings.TrimSpace(title), strings.TrimSpace(content)
+}
+
+var grammarsDir = filepath.Join(codesTextDir, "grammars")
+
+// grammarNameRe is what a grammar name may look like. Names come from upload
+// forms, so anything that could leave grammarsDir is refused.
+var grammarNameRe = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+var (
+	grammarMu sync.Mutex
+	grammars  = map[string]*compiledGrammar{}
+)
+
+// loadGrammar returns the compiled grammar called name, reading it from
+// grammarsDir the first time. An empty name or "cod" is the default grammar.
+func loadGrammar(name string) (*compiledGrammar, error) {
+	if name == "" {
+		name = defaultGrammar.Name
+	}
+	grammarMu.Lock()
+	defer grammarMu.Unlock()
+	if g, ok := grammars[name]; ok {
+		return g, nil
+	}
+	g := defaultGrammar
+	if name != defaultGrammar.Name {
+		if !grammarNameRe.MatchString(name) {
+			return nil, fmt.Errorf("invalid grammar name %q", name)
+		}
+		data, err := os.ReadFile(filepath.Join(grammarsDir, name+".json"))
+		if err != nil {
+			return nil, fmt.Errorf("grammar %s: %v", name, err)
+		}
+		g = Grammar{}
+		if err := json.Unmarshal(data, &g); err != nil {
+			return nil, fmt.Errorf("grammar %s: %v", name, err)
+		}
+		g.Name = name
+	}
+	cg, err := compileGrammar(g)
+	if err != nil {
+		return nil, err
+	}
+	grammars[name] = cg
+	return cg, nil
+}
+
+// grammarFor returns the grammar of a code from codeFiles.
+func grammarFor(codeID string) (*compiledGrammar, error) {
+	return loadGrammar(codeFiles[codeID].grammar)
+}
+
+// registryEntry is an act listed in codurileactualizate/registry.json, which
+// adds acts to codeFiles without code changes:
+//
+//	[{"id": "constitutie", "file": "constitutia.txt", "title": "Constituția României", "grammar": "constitutie"}]
+type registryEntry struct {
+	ID      string `json:"id"`
+	File    string `json:"file"`
+	Title   string `json:"title"`
+	Grammar string `json:"grammar"`
+}
+
+// loadCodeRegistry adds the acts of registry.json to codeFiles. The built-in
+// codes cannot be replaced.
+func loadCodeRegistry() {
+	data, err := os.ReadFile(filepath.Join(codesTextDir, "registry.json"))
+	if err != nil {
+		return
+	}
+	var entries []registryEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		fmt.Println("invalid code registry:", err)
+		return
+	}
+	for _, e := range entries {
+		if e.ID == "" || e.File == "" {
+			continue
+		}
+		if _, ok := codeFiles[e.ID]; ok {
+			fmt.Println("code registry: id already in use -", e.ID)
+			continue
+		}
+		if _, err := loadGrammar(e.Grammar); err != nil {
+			fmt.Println("code registry:", e.ID, "-", err)
+			continue
+		}
+		codeFiles[e.ID] = codeFile{path: filepath.Join(codesTextDir, e.File), title: e.Title, grammar: e.Grammar}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testLegeGrammar = `{
+  "levels": [
+    {"level": "title", "pattern": "(?i)^Titlul\\s+(?:[IVXLC]+|PRELIMINAR|UNIC)\\b"},
+    {"level": "chapter", "pattern": "(?i)^Capitolul\\s+(?:[IVXLC]+(?:\\^\\d+)?|UNIC)\\b"}
+  ],
+  "article": "^Art(?:icolul|\\.)\\s*(?P<number>{number})\\.?\\s*(?:[-–]\\s*(?P<content>.+))?$",
+  "notes": ["(?i)^Not[aă]\\s*:?$"],
+  "references": "(?i)(monitorul oficial|legea nr|ril nr|decizia)"
+}`
+
+// useTestGrammars points grammarsDir to a temporary directory holding the
+// given grammar files and forgets the grammars compiled from it afterwards.
+func useTestGrammars(t *testing.T, files map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name+".json"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := grammarsDir
+	grammarsDir = dir
+	t.Cleanup(func() {
+		grammarsDir = old
+		grammarMu.Lock()
+		for name := range files {
+			delete(grammars, name)
+		}
+		grammarMu.Unlock()
+	})
+}
+
+// Legea nr. 303/2022, art. 1 to 2, with "Art. 1. - text" headings.
+func TestLoadGrammarFile(t *testing.T) {
+	useTestGrammars(t, map[string]string{"lege": testLegeGrammar})
+	g, err := loadGrammar("lege")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := []string{
+		"Capitolul I Dispoziții generale",
+		"Art. 1. - (1) Prezenta lege reglementează statutul judecătorilor și procurorilor.",
+		"(2) Judecătorii și procurorii sunt magistrați.",
+		"Art. 2. - Judecătorii numiți de Președintele României sunt inamovibili.",
+	}
+	pc := parseCodeLines(lines, "lege303", "Legea nr. 303/2022", g)
+	if len(pc.Articles) != 2 {
+		t.Fatalf("parsed %d articles, want 2", len(pc.Articles))
+	}
+	if a := pc.Articles[0]; a.Number != "1" || len(a.Paragraphs) != 2 || !strings.HasPrefix(a.Content, "(1) Prezenta lege") {
+		t.Errorf("art. 1: number %q, %d paragraphs, content %q", a.Number, len(a.Paragraphs), a.Content)
+	}
+}
+
+func TestLoadGrammarInvalidName(t *testing.T) {
+	useTestGrammars(t, map[string]string{"lege": testLegeGrammar})
+	for _, name := range []string{"../grammars/lege", "/etc/passwd", "lege.json", "Lege", "lege/../lege"} {
+		if _, err := loadGrammar(name); err == nil || !strings.Contains(err.Error(), "invalid grammar name") {
+			t.Errorf("loadGrammar(%q): %v, want an invalid name error", name, err)
+		}
+	}
+}
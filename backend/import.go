@@ -0,0 +1,86 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// importCodeHandler returns a handler that turns an uploaded document into
+// the lines of a code text with convert and parses them. Form fields:
+//
+//	file    the document
+//	code    optional code id, used for article IDs and the grammar
+//	grammar optional grammar name, overriding the one of the code
+//	date    optional YYYY-MM-DD; stores the text as a dated version of code
+//
+// The response holds the text, the parsed code and the parser diagnostics.
+func importCodeHandler(convert func(io.Reader) ([]string, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.PostForm("code")
+		info, known := codeFiles[id]
+		if id != "" && !known {
+			c.JSON(http.StatusNotFound, gin.H{"error": "code not found"})
+			return
+		}
+		if id == "" {
+			id = "import"
+		}
+		date := c.PostForm("date")
+		if date != "" {
+			if _, err := time.Parse("2006-01-02", date); err != nil || !known {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "date must be YYYY-MM-DD and requires code"})
+				return
+			}
+		}
+		grammarName := c.PostForm("grammar")
+		if grammarName == "" {
+			grammarName = info.grammar
+		}
+		g, err := loadGrammar(grammarName)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		fh, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing file"})
+			return
+		}
+		f, err := fh.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid file"})
+			return
+		}
+		defer f.Close()
+		lines, err := convert(f)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "could not read document: " + err.Error()})
+			return
+		}
+
+		title := info.title
+		if t := c.PostForm("title"); t != "" {
+			title = t
+		}
+		pc := parseCodeLines(lines, id, title, g)
+		analyzeParsedCode(pc)
+		text := strings.Join(lines, "\n") + "\n"
+
+		if date != "" {
+			dir := filepath.Join(versionsDir, id)
+			os.MkdirAll(dir, os.ModePerm)
+			if err := os.WriteFile(filepath.Join(dir, date+".txt"), []byte(text), 0644); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save"})
+				return
+			}
+			cacheRemove(id + "@" + date)
+		}
+		c.JSON(http.StatusOK, gin.H{"text": text, "parsed": pc, "diagnostics": pc.Diagnostics})
+	}
+}
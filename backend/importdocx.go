@@ -0,0 +1,341 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Limits on what an upload may take in memory: the DOCX file itself and each
+// part once decompressed, so that a small zip bomb cannot exhaust it.
+const (
+	maxDocxSize     = 50 << 20
+	maxDocxPartSize = 100 << 20
+)
+
+// readLimited reads r whole, failing when it holds more than limit bytes.
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("larger than %d MB", limit>>20)
+	}
+	return data, nil
+}
+
+// docxReadFile returns the content of a part of a DOCX package, or nil when
+// the part is missing.
+func docxReadFile(r *zip.Reader, name string) ([]byte, error) {
+	for _, f := range r.File {
+		if f.Name == name {
+			if f.UncompressedSize64 > maxDocxPartSize {
+				return nil, fmt.Errorf("%s is larger than %d MB", name, maxDocxPartSize>>20)
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer rc.Close()
+			// the declared size may lie; the reader is limited as well
+			data, err := readLimited(rc, maxDocxPartSize)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", name, err)
+			}
+			return data, nil
+		}
+	}
+	return nil, nil
+}
+
+// docxLevel is the numbering format of one level of a Word list.
+type docxLevel struct {
+	start   int
+	format  string
+	lvlText string
+}
+
+// docxNumbering rebuilds the labels Word generates for numbered paragraphs
+// ("(1)", "a)"), which are not part of the document text.
+type docxNumbering struct {
+	levels   map[string]map[int]docxLevel // numId -> ilvl -> level
+	counters map[string][]int             // numId -> counter per level
+}
+
+func parseDocxNumbering(data []byte) *docxNumbering {
+	n := &docxNumbering{levels: map[string]map[int]docxLevel{}, counters: map[string][]int{}}
+	if data == nil {
+		return n
+	}
+	type val struct {
+		Val string `xml:"val,attr"`
+	}
+	var doc struct {
+		AbstractNums []struct {
+			ID     string `xml:"abstractNumId,attr"`
+			Levels []struct {
+				Ilvl    int `xml:"ilvl,attr"`
+				Start   val `xml:"start"`
+				NumFmt  val `xml:"numFmt"`
+				LvlText val `xml:"lvlText"`
+			} `xml:"lvl"`
+		} `xml:"abstractNum"`
+		Nums []struct {
+			ID       string `xml:"numId,attr"`
+			Abstract val    `xml:"abstractNumId"`
+		} `xml:"num"`
+	}
+	if xml.Unmarshal(data, &doc) != nil {
+		return n
+	}
+	abstract := map[string]map[int]docxLevel{}
+	for _, a := range doc.AbstractNums {
+		lv := map[int]docxLevel{}
+		for _, l := range a.Levels {
+			start, err := strconv.Atoi(l.Start.Val)
+			if err != nil {
+				start = 1
+			}
+			lv[l.Ilvl] = docxLevel{start: start, format: l.NumFmt.Val, lvlText: l.LvlText.Val}
+		}
+		abstract[a.ID] = lv
+	}
+	for _, num := range doc.Nums {
+		n.levels[num.ID] = abstract[num.Abstract.Val]
+	}
+	return n
+}
+
+// label advances the counter of a list level and returns its label. Deeper
+// levels restart when a level advances.
+func (n *docxNumbering) label(numID string, ilvl int) string {
+	levels := n.levels[numID]
+	lvl, ok := levels[ilvl]
+	if !ok || ilvl < 0 || ilvl > 8 {
+		return ""
+	}
+	c := n.counters[numID]
+	if c == nil {
+		c = make([]int, 9)
+		n.counters[numID] = c
+	}
+	if c[ilvl] == 0 {
+		c[ilvl] = lvl.start
+	} else {
+		c[ilvl]++
+	}
+	for k := ilvl + 1; k < len(c); k++ {
+		c[k] = 0
+	}
+	if lvl.format == "bullet" || lvl.format == "none" {
+		return ""
+	}
+	text := lvl.lvlText
+	for k := 0; k <= ilvl; k++ {
+		v := c[k]
+		if v == 0 {
+			v = levels[k].start
+		}
+		text = strings.ReplaceAll(text, fmt.Sprintf("%%%d", k+1), docxFormatNumber(v, levels[k].format))
+	}
+	return text
+}
+
+func docxFormatNumber(v int, format string) string {
+	switch format {
+	case "lowerLetter", "upperLetter":
+		// a..z, then aa, bb, ... as Word does
+		s := strings.Repeat(string(rune('a'+(v-1)%26)), (v-1)/26+1)
+		if format == "upperLetter" {
+			s = strings.ToUpper(s)
+		}
+		return s
+	case "lowerRoman":
+		return strings.ToLower(romanNumeral(v))
+	case "upperRoman":
+		return romanNumeral(v)
+	}
+	return strconv.Itoa(v)
+}
+
+func romanNumeral(v int) string {
+	vals := []int{1000, 900, 500, 400, 100, 90, 50, 40, 10, 9, 5, 4, 1}
+	syms := []string{"M", "CM", "D", "CD", "C", "XC", "L", "XL", "X", "IX", "V", "IV", "I"}
+	var b strings.Builder
+	for i, n := range vals {
+		for v >= n {
+			b.WriteString(syms[i])
+			v -= n
+		}
+	}
+	return b.String()
+}
+
+// docxParagraph is a paragraph of the document being read.
+type docxParagraph struct {
+	text     strings.Builder
+	bold     int // characters in bold runs
+	plain    int // characters in other runs
+	heading  bool
+	numID    string
+	ilvl     int
+	numbered bool
+}
+
+// docxOn reports whether a toggle property such as <w:b/> is switched on.
+func docxOn(e xml.StartElement) bool {
+	for _, a := range e.Attr {
+		if a.Name.Local == "val" {
+			return a.Value != "0" && a.Value != "false" && a.Value != "off"
+		}
+	}
+	return true
+}
+
+func docxAttr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// docxToLines extracts the text of a Word document as the lines of a code
+// text file: one line per paragraph, with the labels of numbered paragraphs
+// restored. Headings split over two bold paragraphs ("Capitolul I" and its
+// name) are joined on one line like in the .txt files.
+func docxToLines(r io.Reader) ([]string, error) {
+	data, err := readLimited(r, maxDocxSize)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	doc, err := docxReadFile(zr, "word/document.xml")
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, fmt.Errorf("word/document.xml not found")
+	}
+	numData, err := docxReadFile(zr, "word/numbering.xml")
+	if err != nil {
+		return nil, err
+	}
+	numbering := parseDocxNumbering(numData)
+
+	var lines []string
+	var boldLines []bool
+	emit := func(text string, bold bool) {
+		for _, l := range strings.Split(text, "\n") {
+			if l = strings.Join(strings.Fields(l), " "); l == "" {
+				continue
+			}
+			// "Capitolul I" followed by its name in bold
+			if n := len(lines) - 1; n >= 0 && bold && boldLines[n] && isHeadingLabel(lines[n]) && !tocHeadingRe.MatchString(l) && !strings.HasPrefix(strings.ToLower(l), "art") {
+				lines[n] += " " + l
+				boldLines[n] = false
+				continue
+			}
+			lines = append(lines, l)
+			boldLines = append(boldLines, bold)
+		}
+	}
+
+	dec := xml.NewDecoder(bytes.NewReader(doc))
+	var p *docxParagraph
+	var runBold, inText, inPPr bool
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				p = &docxParagraph{}
+			case "pPr":
+				inPPr = true
+			case "pStyle":
+				if p != nil {
+					s := strings.ToLower(docxAttr(t, "val"))
+					p.heading = strings.HasPrefix(s, "heading") || strings.HasPrefix(s, "titlu") || s == "title"
+				}
+			case "numId":
+				if p != nil && inPPr {
+					p.numID = docxAttr(t, "val")
+					p.numbered = p.numID != "" && p.numID != "0"
+				}
+			case "ilvl":
+				if p != nil && inPPr {
+					p.ilvl, _ = strconv.Atoi(docxAttr(t, "val"))
+				}
+			case "r":
+				runBold = false
+			case "b":
+				if !inPPr {
+					runBold = docxOn(t)
+				}
+			case "t":
+				inText = true
+			case "tab":
+				if p != nil && !inPPr {
+					p.text.WriteString(" ")
+				}
+			case "br", "cr":
+				if p != nil {
+					p.text.WriteString("\n")
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "pPr":
+				inPPr = false
+			case "t":
+				inText = false
+			case "p":
+				if p == nil {
+					continue
+				}
+				text := p.text.String()
+				if p.numbered {
+					if label := numbering.label(p.numID, p.ilvl); label != "" {
+						text = label + " " + text
+					}
+				}
+				emit(text, p.heading || (p.bold > 0 && p.plain == 0))
+				p = nil
+			}
+		case xml.CharData:
+			if inText && p != nil {
+				p.text.Write(t)
+				n := len(strings.TrimSpace(string(t)))
+				if runBold {
+					p.bold += n
+				} else {
+					p.plain += n
+				}
+			}
+		}
+	}
+	return lines, nil
+}
+
+// isHeadingLabel reports whether a line is only the label of a heading
+// ("Capitolul I", "Secțiunea a 2-a"), without its name.
+func isHeadingLabel(line string) bool {
+	level, _ := headingKey(line)
+	return level != "" && len(strings.Fields(line)) <= 3
+}
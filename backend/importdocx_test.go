@@ -0,0 +1,116 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const testDocxNumbering = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:numbering xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+<w:abstractNum w:abstractNumId="0">
+<w:lvl w:ilvl="0"><w:start w:val="1"/><w:numFmt w:val="decimal"/><w:lvlText w:val="(%1)"/></w:lvl>
+<w:lvl w:ilvl="1"><w:start w:val="1"/><w:numFmt w:val="lowerLetter"/><w:lvlText w:val="%2)"/></w:lvl>
+</w:abstractNum>
+<w:num w:numId="1"><w:abstractNumId w:val="0"/></w:num>
+</w:numbering>`
+
+// testDocxDocument wraps the given paragraphs in a word/document.xml.
+func testDocxDocument(paragraphs ...string) string {
+	return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		strings.Join(paragraphs, "\n") + `</w:body></w:document>`
+}
+
+func boldParagraph(text string) string {
+	return `<w:p><w:r><w:rPr><w:b/></w:rPr><w:t>` + text + `</w:t></w:r></w:p>`
+}
+
+func plainParagraph(text string) string {
+	return `<w:p><w:r><w:t xml:space="preserve">` + text + `</w:t></w:r></w:p>`
+}
+
+func numberedParagraph(ilvl, text string) string {
+	return `<w:p><w:pPr><w:numPr><w:ilvl w:val="` + ilvl + `"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>` + text + `</w:t></w:r></w:p>`
+}
+
+func testDocx(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDocxToLines(t *testing.T) {
+	doc := testDocxDocument(
+		boldParagraph("Capitolul I"),
+		boldParagraph("Dispoziții generale"),
+		boldParagraph("Articolul 16"),
+		boldParagraph("Vinovăția"),
+		numberedParagraph("0", "Fapta constituie infracțiune numai dacă este săvârșită cu forma de vinovăție cerută de legea penală."),
+		numberedParagraph("0", "Vinovăție există când fapta este comisă cu intenție, din culpă sau cu intenție depășită."),
+		numberedParagraph("0", "Fapta este comisă cu intenție când infractorul:"),
+		numberedParagraph("1", "prevede rezultatul faptei sale, urmărind producerea lui prin săvârșirea faptei;"),
+		numberedParagraph("1", "prevede rezultatul faptei sale și, deși nu-l urmărește, acceptă posibilitatea producerii lui."),
+		numberedParagraph("0", "Fapta este comisă din culpă când infractorul:"),
+		numberedParagraph("1", "prevede rezultatul faptei sale, dar nu-l acceptă;"),
+		plainParagraph("Text   cu <w:tab/>spații"),
+	)
+	data := testDocx(t, map[string]string{"word/document.xml": doc, "word/numbering.xml": testDocxNumbering})
+	lines, err := docxToLines(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"Capitolul I Dispoziții generale",
+		"Articolul 16",
+		"Vinovăția",
+		"(1) Fapta constituie infracțiune numai dacă este săvârșită cu forma de vinovăție cerută de legea penală.",
+		"(2) Vinovăție există când fapta este comisă cu intenție, din culpă sau cu intenție depășită.",
+		"(3) Fapta este comisă cu intenție când infractorul:",
+		"a) prevede rezultatul faptei sale, urmărind producerea lui prin săvârșirea faptei;",
+		"b) prevede rezultatul faptei sale și, deși nu-l urmărește, acceptă posibilitatea producerii lui.",
+		"(4) Fapta este comisă din culpă când infractorul:",
+		"a) prevede rezultatul faptei sale, dar nu-l acceptă;",
+		"Text cu spații",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("docxToLines =\n%s\nwant\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// A part that declares a size over the limit is rejected before it is
+// decompressed.
+func TestDocxToLinesLimits(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.CreateRaw(&zip.FileHeader{Name: "word/document.xml", Method: zip.Store, CompressedSize64: 2, UncompressedSize64: maxDocxPartSize + 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("<a"))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := docxToLines(bytes.NewReader(buf.Bytes())); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("oversized part: %v, want an error", err)
+	}
+
+	if _, err := readLimited(strings.NewReader("0123456789"), 9); err == nil {
+		t.Error("readLimited over the limit: no error")
+	}
+	if data, err := readLimited(strings.NewReader("0123456789"), 10); err != nil || len(data) != 10 {
+		t.Errorf("readLimited at the limit: %d bytes, %v", len(data), err)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"golang.org/x/net/html"
+	"io"
+	"strings"
+)
+
+// HTML pages saved from legislatie.just.ro wrap every part of the text in a
+// span whose class names its role: "S_CAP" holds a chapter with its label in
+// "S_CAP_TTL" ("Capitolul II") and its name in "S_CAP_DEN", "S_ART" an article
+// with "S_ART_TTL" ("Articolul 3"), "S_ART_DEN" and "S_ART_BDY", "S_ALN" a
+// paragraph with "S_ALN_TTL" ("(1)") and "S_ALN_BDY", and so on. The importer
+// lays these out the way the .txt files do: heading label and name on one
+// line, the article label and name on lines of their own, paragraph and
+// letter labels in front of their text.
+
+// elements that never hold text of the act
+var htmlSkipTags = map[string]bool{
+	"head": true, "script": true, "style": true, "noscript": true, "nav": true,
+	"header": true, "footer": true, "form": true, "button": true, "select": true,
+	"input": true, "iframe": true, "svg": true, "img": true,
+}
+
+// id and class fragments of the portal's page chrome
+var htmlChromeMarkers = []string{"menu", "navbar", "breadcrumb", "footer", "header", "cookie", "banner", "sidebar", "toolbar", "search"}
+
+// elements that start a new line
+var htmlBlockTags = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true, "table": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"section": true, "article": true, "blockquote": true, "pre": true, "hr": true,
+}
+
+// containers of headings and articles
+var htmlHeadingRoles = map[string]bool{
+	"S_PRT": true, "S_CRT": true, "S_TTL": true, "S_CAP": true, "S_SEC": true, "S_SSEC": true, "S_ART": true,
+}
+
+// ids of the element holding the text of the act on the portal pages
+var htmlContentIDs = []string{"textdocumentleg", "div_Formaconsolidata"}
+
+func htmlAttr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// htmlRole returns the legislatie.just.ro class of a node ("S_ART_TTL"), if any.
+func htmlRole(n *html.Node) string {
+	for _, c := range strings.Fields(htmlAttr(n, "class")) {
+		if strings.HasPrefix(c, "S_") {
+			return c
+		}
+	}
+	return ""
+}
+
+func htmlIsChrome(n *html.Node) bool {
+	if htmlSkipTags[n.Data] {
+		return true
+	}
+	if htmlRole(n) != "" {
+		return false
+	}
+	attrs := strings.ToLower(htmlAttr(n, "id") + " " + htmlAttr(n, "class"))
+	for _, m := range htmlChromeMarkers {
+		if strings.Contains(attrs, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// htmlContentRoot finds the element holding the text of the act, falling
+// back to the body, or the whole document.
+func htmlContentRoot(doc *html.Node) *html.Node {
+	var body, found *html.Node
+	var find func(*html.Node)
+	find = func(n *html.Node) {
+		if found != nil {
+			return
+		}
+		if n.Type == html.ElementNode {
+			if n.Data == "body" && body == nil {
+				body = n
+			}
+			id, class := htmlAttr(n, "id"), htmlAttr(n, "class")
+			for _, c := range htmlContentIDs {
+				if strings.EqualFold(id, c) || strings.Contains(class, c) {
+					found = n
+					return
+				}
+			}
+		}
+		for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+			find(ch)
+		}
+	}
+	find(doc)
+	switch {
+	case found != nil:
+		return found
+	case body != nil:
+		return body
+	}
+	return doc
+}
+
+// htmlBreakBefore reports whether a role starts a new line. Labels always do;
+// names follow the label on the same line except for articles, and bodies
+// follow the label of paragraphs and letters.
+func htmlBreakBefore(role string) bool {
+	switch {
+	case role == "":
+		return false
+	case strings.HasSuffix(role, "_TTL"):
+		return true
+	case strings.HasSuffix(role, "_DEN"):
+		return role == "S_ART_DEN"
+	case strings.HasSuffix(role, "_BDY"):
+		return role != "S_ALN_BDY" && role != "S_LIT_BDY" && role != "S_PCT_BDY"
+	}
+	return true
+}
+
+// htmlToLines extracts the text of a legal act from an HTML page as the lines
+// of a code text file, ready for parseCodeLines.
+func htmlToLines(r io.Reader) ([]string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	var cur strings.Builder
+	flush := func() {
+		if line := strings.Join(strings.Fields(cur.String()), " "); line != "" {
+			lines = append(lines, line)
+		}
+		cur.Reset()
+	}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			cur.WriteString(n.Data)
+			return
+		case html.ElementNode:
+			if htmlIsChrome(n) {
+				return
+			}
+			role := htmlRole(n)
+			block := htmlBlockTags[n.Data] || htmlBreakBefore(role)
+			// a label only starts its line, the name or body follows it
+			breakAfter := block && !strings.HasSuffix(role, "_TTL")
+			if block {
+				flush()
+				if htmlHeadingRoles[role] && len(lines) > 0 && lines[len(lines)-1] != "" {
+					// headings and articles are set apart like in the .txt files
+					lines = append(lines, "")
+				}
+			} else if role != "" && cur.Len() > 0 {
+				cur.WriteString(" ")
+			}
+			for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+				walk(ch)
+			}
+			if breakAfter {
+				flush()
+			}
+			return
+		}
+		for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+			walk(ch)
+		}
+	}
+	walk(htmlContentRoot(doc))
+	flush()
+	return lines, nil
+}
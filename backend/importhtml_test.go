@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// testdata/import/codul_penal.html is a page saved from legislatie.just.ro,
+// cut down to a few articles of Codul penal, with the portal menus, search
+// form, list of forms and footer around the text.
+const importedCodeText = `CODUL PENAL din 17 iulie 2009 (*actualizat*)
+EMITENT: PARLAMENTUL
+
+Partea GENERALĂ
+
+Titlul I Legea penală și limitele ei de aplicare
+
+Capitolul I Principii generale
+
+Articolul 1
+Legalitatea incriminării
+(1) Legea penală prevede faptele care constituie infracțiuni.
+(2) Nicio persoană nu poate fi sancționată penal pentru o faptă care nu era prevăzută de legea penală la data când a fost săvârșită.
+
+Capitolul II Aplicarea legii penale
+
+Secţiunea 1 Aplicarea legii penale în timp
+
+Articolul 5
+Aplicarea legii penale mai favorabile până la judecarea definitivă a cauzei
+(1) În cazul în care de la săvârșirea infracțiunii până la judecarea definitivă a cauzei au intervenit una sau mai multe legi penale, se aplică legea mai favorabilă.
+Notă
+Decizie de admitere: HP nr. 21/2014, publicată în Monitorul Oficial nr. 829 din 13 noiembrie 2014.
+(2) Dispozițiile alin. (1) se aplică și actelor normative ori prevederilor din acestea declarate neconstituționale.
+
+Articolul 6
+Aplicarea legii penale mai favorabile după judecarea definitivă a cauzei
+(1) Când după rămânerea definitivă a hotărârii de condamnare și până la executarea completă a pedepsei:
+a) a intervenit o lege care prevede o pedeapsă mai ușoară;
+b) a intervenit o lege care nu mai prevede fapta ca infracțiune.
+(la 18-08-2022, Alineatul (1) din Articolul 6 a fost modificat de Punctul 2, Articolul 7 din LEGEA nr. 140 din 17 mai 2022, publicată în MONITORUL OFICIAL nr. 500 din 20 mai 2022 )`
+
+func TestHTMLToLines(t *testing.T) {
+	f, err := os.Open("testdata/import/codul_penal.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	lines, err := htmlToLines(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(lines, "\n"); got != importedCodeText {
+		t.Errorf("htmlToLines =\n%s\nwant\n%s", got, importedCodeText)
+	}
+
+	g, err := loadGrammar("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc := parseCodeLines(lines, "penal", "Codul Penal", g)
+	if len(pc.Parts) != 1 || len(pc.Books) != 1 || len(pc.Books[0].Titles) != 1 || len(pc.Books[0].Titles[0].Chapters) != 2 {
+		t.Fatalf("parsed %d parts and books %+v, want one part with one title of two chapters", len(pc.Parts), pc.Books)
+	}
+	if sec := pc.Books[0].Titles[0].Chapters[1].Sections[0]; sec.Title != "Secţiunea 1" || sec.Subtitle != "Aplicarea legii penale în timp" || len(sec.Articles) != 2 {
+		t.Errorf("section %q %q with %d articles", sec.Title, sec.Subtitle, len(sec.Articles))
+	}
+	var numbers []string
+	for _, a := range pc.Articles {
+		numbers = append(numbers, a.Number)
+	}
+	if strings.Join(numbers, ",") != "1,5,6" {
+		t.Fatalf("articles %q, want 1, 5 and 6", numbers)
+	}
+	if a := pc.Articles[1]; len(a.Paragraphs) != 2 || len(a.Paragraphs[0].Notes) != 1 || !strings.Contains(a.Paragraphs[0].Notes[0], "HP nr. 21/2014") {
+		t.Errorf("art. 5: %d paragraphs, notes of the first %q", len(a.Paragraphs), a.Paragraphs[0].Notes)
+	}
+	if a := pc.Articles[2]; len(a.Paragraphs) != 1 || len(a.Paragraphs[0].Letters) != 2 || len(a.References) != 1 {
+		t.Errorf("art. 6: %d paragraphs, %d references", len(a.Paragraphs), len(a.References))
+	}
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// keywords kept per article
+const maxKeywords = 8
+
+// foldDiacritics lowercases a word and strips the Romanian diacritics, so
+// that "infracțiune", "infracţiune" and "infractiune" are the same term.
+var foldDiacritics = strings.NewReplacer("ă", "a", "â", "a", "î", "i", "ș", "s", "ş", "s", "ț", "t", "ţ", "t")
+
+func foldWord(w string) string {
+	return foldDiacritics.Replace(strings.ToLower(w))
+}
+
+// romanianStopWords are function words and the words every article uses to
+// refer to the law, in folded form.
+var romanianStopWords = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`
+		acea aceasta acei aceia acel acela acele acelasi aceleasi acesta aceste acestea acestei acestia acestor acestora acestui
+		acolo acum adica ai aiba aibe al ala ale alt alta alte altei alti altor altora altul am ar are aria as asa asemenea asupra ati atat
+		atata atatea atatia atunci au avea avem avand aveti avut azi aici buna ca cam cand care careia carora caruia cat cata cate cati catre
+		ce cea cei cel cele celor ceva chiar cine cineva cu cum cumva da daca dar de deci deja desi despre din dintr dintre doar ei el ele
+		era erau este eu fara fata fi fie fiecare fiind fim fost fosti fusese ii il ilor in inca incat intr intre insa isi iar la le li lor lui
+		ma mai mult multe multi ne nici niciun nicio noi nor nostru nu o oare ori oricare orice pana pe prin printr sa sai sale sau se si
+		sub sunt suntem sunteti sus ta tale te tine toata toate tot toti totusi tu un una unde unei unele uneori unii unor unui unul va vor
+		voi vom vreo vreun
+		art articol articolul articolului articolele alin alineat alineatul alineatului lit litera pct punct punctul teza prezentul prezentului
+		prezenta prezentei cod codul codului lege legea legii legi legilor dispozitiile dispozitiilor prevazut prevazuta prevazute prevazuti
+		potrivit conform cazul cazurile abrogat abrogata republicat monitorul oficial privind precum respectiv`) {
+		romanianStopWords[w] = true
+	}
+}
+
+// keywordTerms splits a text into folded terms, dropping numbers, short
+// words and stop-words. surface receives the lowercased form of each term.
+func keywordTerms(text string, surface func(term, word string)) []string {
+	var terms []string
+	for _, w := range strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) }) {
+		t := foldWord(w)
+		if len([]rune(t)) < 3 || romanianStopWords[t] {
+			continue
+		}
+		if surface != nil {
+			surface(t, strings.ToLower(w))
+		}
+		terms = append(terms, t)
+	}
+	return terms
+}
+
+// scoreArticles fills the keywords and importance of every article of a
+// code, then applies the overrides set by admins.
+//
+// Keywords are the terms of the title and text of an article with the highest
+// TF-IDF weight across the articles of the code; title words count twice.
+// They are returned with their diacritics, in the form used most often.
+//
+// Importance is the number of other articles of the code citing the article
+// plus twice the number of exam questions in tests.json referring to it. The
+// top tenth of the scored articles are marked IsImportant.
+func scoreArticles(code *ParsedCode) {
+	type doc struct {
+		tf    map[string]int
+		total int
+	}
+	var docs []doc
+	df := map[string]int{}
+	forms := map[string]map[string]int{}
+	surface := func(t, w string) {
+		if forms[t] == nil {
+			forms[t] = map[string]int{}
+		}
+		forms[t][w]++
+	}
+	walkArticles(code, func(a *Article) {
+		d := doc{tf: map[string]int{}}
+		for _, t := range keywordTerms(a.Title, surface) {
+			d.tf[t] += 2
+			d.total += 2
+		}
+		for _, t := range keywordTerms(a.Content, surface) {
+			d.tf[t]++
+			d.total++
+		}
+		for t := range d.tf {
+			df[t]++
+		}
+		docs = append(docs, d)
+	})
+	form := func(t string) string {
+		best, n := t, 0
+		for w, c := range forms[t] {
+			if c > n || (c == n && w < best) {
+				best, n = w, c
+			}
+		}
+		return best
+	}
+
+	citedBy := map[string]map[string]bool{}
+	for _, a := range code.Articles {
+		for _, ct := range a.Citations {
+			if ct.Source != "content" || ct.Code != code.ID || ct.TargetID == "" || ct.TargetID == a.ID {
+				continue
+			}
+			if citedBy[ct.TargetID] == nil {
+				citedBy[ct.TargetID] = map[string]bool{}
+			}
+			citedBy[ct.TargetID][a.ID] = true
+		}
+	}
+	exam := examQuestionCounts(code)
+
+	i := 0
+	var scores []int
+	walkArticles(code, func(a *Article) {
+		d := docs[i]
+		i++
+		type weighted struct {
+			term string
+			w    float64
+		}
+		var ws []weighted
+		for t, n := range d.tf {
+			if idf := math.Log(float64(len(docs)) / float64(df[t])); idf > 0 {
+				ws = append(ws, weighted{t, float64(n) / float64(d.total) * idf})
+			}
+		}
+		sort.Slice(ws, func(x, y int) bool {
+			if ws[x].w != ws[y].w {
+				return ws[x].w > ws[y].w
+			}
+			return ws[x].term < ws[y].term
+		})
+		a.Keywords = nil
+		for k := 0; k < len(ws) && k < maxKeywords; k++ {
+			a.Keywords = append(a.Keywords, form(ws[k].term))
+		}
+
+		a.CitedBy = len(citedBy[a.ID])
+		a.ExamQuestions = exam[normalizeArticleNumber(a.Number)]
+		a.Importance = a.CitedBy + 2*a.ExamQuestions
+		if a.Importance > 0 {
+			scores = append(scores, a.Importance)
+		}
+	})
+
+	threshold := math.MaxInt32
+	if len(scores) > 0 {
+		sort.Sort(sort.Reverse(sort.IntSlice(scores)))
+		threshold = scores[len(scores)/10]
+	}
+	overrides := articleOverridesSnapshot()
+	walkArticles(code, func(a *Article) {
+		a.IsImportant = a.Importance > 0 && a.Importance >= threshold
+		if o, ok := overrides[a.ID]; ok {
+			if o.Keywords != nil {
+				a.Keywords = o.Keywords
+			}
+			if o.IsImportant != nil {
+				a.IsImportant = *o.IsImportant
+			}
+		}
+	})
+	collectArticles(code)
+}
+
+// examQuestion is the part of a question of tests.json used for scoring.
+// Questions name the articles they are about either in Articles, as numbers
+// and ranges ("12-15"), or in their explanation.
+type examQuestion struct {
+	Text        string   `json:"text"`
+	Note        string   `json:"note"`
+	Explanation string   `json:"explanation"`
+	Subject     string   `json:"subject"`
+	Articles    []string `json:"articles"`
+}
+
+// longer ranges of Articles are too vague to credit every article
+const maxExamRange = 30
+
+// examQuestionCounts counts, per article number of the code, the questions
+// of tests.json that refer to the article.
+func examQuestionCounts(code *ParsedCode) map[string]int {
+	counts := map[string]int{}
+	data, err := os.ReadFile(filepath.Join(dataDir, "tests.json"))
+	if err != nil {
+		if data, err = os.ReadFile(filepath.Join(rootDir, "backend", "tests.json")); err != nil {
+			return counts
+		}
+	}
+	var tests []struct {
+		Subject   string         `json:"subject"`
+		Questions []examQuestion `json:"questions"`
+	}
+	if json.Unmarshal(data, &tests) != nil {
+		return counts
+	}
+	for _, t := range tests {
+		for _, q := range t.Questions {
+			subject := q.Subject
+			if subject == "" {
+				subject = t.Subject
+			}
+			if subjectCodeID(subject) != code.ID {
+				continue
+			}
+			seen := map[string]bool{}
+			for _, r := range q.Articles {
+				from, to, _ := strings.Cut(r, "-")
+				a, err1 := strconv.Atoi(strings.TrimSpace(from))
+				b, err2 := strconv.Atoi(strings.TrimSpace(to))
+				if err1 != nil {
+					continue
+				}
+				if err2 != nil || b < a || b-a > maxExamRange {
+					b = a
+				}
+				for n := a; n <= b; n++ {
+					seen[strconv.Itoa(n)] = true
+				}
+			}
+			if len(q.Articles) == 0 {
+				for _, ct := range findCitations(q.Explanation+"\n"+q.Note+"\n"+q.Text, code.ID, "", "test") {
+					if ct.Code == code.ID {
+						seen[ct.Article] = true
+					}
+				}
+			}
+			for n := range seen {
+				counts[n]++
+			}
+		}
+	}
+	return counts
+}
+
+// subjectCodeID maps the subject of a test ("Drept procesual penal", or the
+// short ids of the question bank) to a code id.
+func subjectCodeID(subject string) string {
+	s := foldWord(subject)
+	switch s {
+	case "dpc":
+		return "proc_civil"
+	case "dpp":
+		return "proc_penal"
+	}
+	if strings.Contains(s, "procesual") || strings.Contains(s, "procedur") {
+		s = "procedura " + s
+	}
+	return citedCodeID(s)
+}
+
+// ArticleOverride holds the values an admin set by hand for an article. Nil
+// fields keep the computed value; an empty keyword list clears the keywords.
+type ArticleOverride struct {
+	Keywords    []string `json:"keywords"`
+	IsImportant *bool    `json:"isImportant"`
+}
+
+var articleOverridesFile = filepath.Join(dataDir, "article_overrides.json")
+
+var (
+	overridesMu     sync.Mutex
+	overridesLoaded bool
+	articleOverride = map[string]ArticleOverride{}
+)
+
+// articleOverridesSnapshot returns a copy of the overrides, keyed by article
+// ID, reading them from disk the first time.
+func articleOverridesSnapshot() map[string]ArticleOverride {
+	overridesMu.Lock()
+	defer overridesMu.Unlock()
+	if !overridesLoaded {
+		if data, err := os.ReadFile(articleOverridesFile); err == nil {
+			json.Unmarshal(data, &articleOverride)
+		}
+		overridesLoaded = true
+	}
+	out := make(map[string]ArticleOverride, len(articleOverride))
+	for k, v := range articleOverride {
+		out[k] = v
+	}
+	return out
+}
+
+func saveArticleOverrides() error {
+	data, err := json.MarshalIndent(articleOverride, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(articleOverridesFile), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(articleOverridesFile, data, 0644)
+}
+
+// articleOverrideHandler reads (GET), sets (PUT) or removes (DELETE) the
+// override of an article's keywords and importance. Changes are applied to
+// the stored parsed code right away.
+func articleOverrideHandler(c *gin.Context) {
+	id := c.Param("id")
+	pc, err := loadParsedCode(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "code not found"})
+		return
+	}
+	art := findArticle(pc, c.Param("number"))
+	if art == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "article not found"})
+		return
+	}
+	articleID := art.ID
+	overrides := articleOverridesSnapshot()
+	if c.Request.Method == http.MethodGet {
+		c.JSON(http.StatusOK, overrides[articleID])
+		return
+	}
+
+	var o ArticleOverride
+	if c.Request.Method == http.MethodPut {
+		if err := c.BindJSON(&o); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+	}
+	overridesMu.Lock()
+	if c.Request.Method == http.MethodDelete || (o.Keywords == nil && o.IsImportant == nil) {
+		delete(articleOverride, articleID)
+	} else {
+		articleOverride[articleID] = o
+	}
+	err = saveArticleOverrides()
+	overridesMu.Unlock()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	scoreArticles(pc)
+	data, err := json.MarshalIndent(pc, "", "  ")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	jsonPath := filepath.Join(rootDir, "dashbord-react", "code_"+id+".json")
+	if err := os.WriteFile(jsonPath, data, 0644); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	cacheAdd(id, pc)
+	invalidateCitationGraph()
+	invalidateGlossary()
+	reindexCode(id, pc)
+	invalidateSuggestions(id)
+	for i := range pc.Articles {
+		if pc.Articles[i].ID == articleID {
+			c.JSON(http.StatusOK, pc.Articles[i])
+			return
+		}
+	}
+	c.Status(http.StatusOK)
+}
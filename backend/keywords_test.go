@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const keywordsCodeText = `Articolul 1
+
+Furtul
+(1) Luarea unui bun mobil din posesia sau detenția altuia, fără consimțământul acestuia, în scopul de a și-l însuși pe nedrept, se pedepsește.
+
+Articolul 2
+
+Tâlhăria
+(1) Furtul săvârșit prin întrebuințare de violențe sau amenințări se pedepsește.
+(2) Dispozițiile art. 1 privind bunul mobil se aplică în mod corespunzător.
+
+Articolul 3
+
+Înșelăciunea
+(1) Inducerea în eroare a unei persoane prin prezentarea ca adevărată a unei fapte mincinoase se pedepsește.
+(2) Dispozițiile art. 1 se aplică în mod corespunzător.
+`
+
+func TestKeywordTerms(t *testing.T) {
+	forms := map[string]string{}
+	got := keywordTerms("Legea penală se aplică infracțiunilor săvârșite în 2014, potrivit art. 5 din Codul penal.", func(term, word string) {
+		forms[term] = word
+	})
+	want := []string{"penala", "aplica", "infractiunilor", "savarsite", "penal"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("keywordTerms = %q, want %q", got, want)
+	}
+	if forms["infractiunilor"] != "infracțiunilor" {
+		t.Errorf("surface form %q", forms["infractiunilor"])
+	}
+}
+
+// useKeywordsCode parses keywordsCodeText as the code "test" and keeps the
+// overrides in a temporary file for the duration of the test.
+func useKeywordsCode(t *testing.T) string {
+	t.Helper()
+	jsonPath := useTestCode(t, keywordsCodeText)
+	oldFile := articleOverridesFile
+	articleOverridesFile = filepath.Join(t.TempDir(), "data", "article_overrides.json")
+	overridesMu.Lock()
+	oldOverrides, oldLoaded := articleOverride, overridesLoaded
+	articleOverride, overridesLoaded = map[string]ArticleOverride{}, true
+	overridesMu.Unlock()
+	t.Cleanup(func() {
+		articleOverridesFile = oldFile
+		overridesMu.Lock()
+		articleOverride, overridesLoaded = oldOverrides, oldLoaded
+		overridesMu.Unlock()
+		reindexCode("test", nil)
+		invalidateSuggestions("test")
+	})
+	return jsonPath
+}
+
+func TestScoreArticles(t *testing.T) {
+	useKeywordsCode(t)
+	pc, err := loadParsedCode("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pc.Articles) != 3 {
+		t.Fatalf("parsed %d articles, want 3", len(pc.Articles))
+	}
+	for _, a := range pc.Articles {
+		for _, k := range a.Keywords {
+			// in every article, or a stop-word
+			if k == "pedepsește" || k == "dispozițiile" || k == "art" || k == "unei" {
+				t.Errorf("art. %s: keyword %q", a.Number, k)
+			}
+		}
+	}
+	// the title counts twice and keeps its diacritics
+	if k := pc.Articles[2].Keywords; len(k) == 0 || k[0] != "înșelăciunea" {
+		t.Errorf("art. 3 keywords %q, want înșelăciunea first", k)
+	}
+	if len(pc.Articles[0].Keywords) > maxKeywords {
+		t.Errorf("art. 1 has %d keywords", len(pc.Articles[0].Keywords))
+	}
+	// cited by art. 2 and 3
+	if a := pc.Articles[0]; a.CitedBy != 2 || a.Importance != 2 || !a.IsImportant {
+		t.Errorf("art. 1 cited by %d, importance %d, important %v", a.CitedBy, a.Importance, a.IsImportant)
+	}
+	if a := pc.Articles[1]; a.CitedBy != 0 || a.IsImportant {
+		t.Errorf("art. 2 cited by %d, important %v", a.CitedBy, a.IsImportant)
+	}
+}
+
+func TestArticleOverrideHandler(t *testing.T) {
+	jsonPath := useKeywordsCode(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/codes/:id/articles/:number/overrides", articleOverrideHandler)
+	r.PUT("/codes/:id/articles/:number/overrides", articleOverrideHandler)
+	r.DELETE("/codes/:id/articles/:number/overrides", articleOverrideHandler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("PUT", "/codes/test/articles/2/overrides", strings.NewReader(`{"keywords": ["tâlhărie", "violență"], "isImportant": true}`)))
+	var a Article
+	if err := json.Unmarshal(w.Body.Bytes(), &a); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("PUT: status %d: %s", w.Code, w.Body)
+	}
+	if !reflect.DeepEqual(a.Keywords, []string{"tâlhărie", "violență"}) || !a.IsImportant {
+		t.Errorf("PUT returned keywords %q, important %v", a.Keywords, a.IsImportant)
+	}
+	if _, err := os.Stat(articleOverridesFile); err != nil {
+		t.Errorf("overrides not saved: %v", err)
+	}
+
+	// the stored file, the cached code and the search index follow
+	var stored ParsedCode
+	data, err := os.ReadFile(jsonPath)
+	if err != nil || json.Unmarshal(data, &stored) != nil || !stored.Articles[1].IsImportant {
+		t.Errorf("stored code not updated: %v", err)
+	}
+	cached, ok := cacheGet("test")
+	if !ok || !reflect.DeepEqual(cached.Articles[1].Keywords, []string{"tâlhărie", "violență"}) {
+		t.Errorf("cached code not updated")
+	}
+	searchMu.Lock()
+	_, indexed := searchIndexes["test"]
+	searchMu.Unlock()
+	if !indexed {
+		t.Error("search index not rebuilt")
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/codes/test/articles/2/overrides", nil))
+	if !strings.Contains(w.Body.String(), `"isImportant":true`) {
+		t.Errorf("GET: %s", w.Body)
+	}
+
+	// removing the override restores the computed values
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("DELETE", "/codes/test/articles/2/overrides", nil))
+	a = Article{}
+	json.Unmarshal(w.Body.Bytes(), &a)
+	if w.Code != http.StatusOK || a.IsImportant || reflect.DeepEqual(a.Keywords, []string{"tâlhărie", "violență"}) {
+		t.Errorf("DELETE: status %d, keywords %q, important %v", w.Code, a.Keywords, a.IsImportant)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("PUT", "/codes/test/articles/9/overrides", strings.NewReader(`{}`)))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown article: status %d", w.Code)
+	}
+}
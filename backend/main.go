@@ -178,10 +178,15 @@ var userConversations = make(map[string][]*Conversation)
 var wsClients = make(map[string]*websocket.Conn)
 
 // mapping of available code text files
-var codeFiles = map[string]struct {
-	path  string
-	title string
-}{
+// codeFile is an entry of the code registry: the source text of an act, its
+// title and the grammar used to parse it (empty for the codes' layout).
+type codeFile struct {
+	path    string
+	title   string
+	grammar string
+}
+
+var codeFiles = map[string]codeFile{
 	"civil":      {path: filepath.Join(codesTextDir, "codulcivil.txt"), title: "Codul Civil"},
 	"penal":      {path: filepath.Join(codesTextDir, "codulpenal.txt"), title: "Codul Penal"},
 	"proc_civil": {path: filepath.Join(codesTextDir, "coduldeproceduracivila.txt"), title: "Codul de Procedură Civilă"},
@@ -234,6 +239,15 @@ func cacheAdd(id string, pc *ParsedCode) {
 	}
 }
 
+func cacheRemove(id string) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if c, ok := parsedCache[id]; ok {
+		parsedOrder.Remove(c.elem)
+		delete(parsedCache, id)
+	}
+}
+
 type SimpleCode struct {
 	ID          string `json:"id"`
 	Title       string `json:"title"`
@@ -282,44 +296,64 @@ func saveCodes() {
 	_ = os.WriteFile(codesFile, data, 0644)
 }
 
-// preloadParsedCodes ensures all known code files are parsed once at startup
-// and cached as JSON files next to the React dashboard. This avoids expensive
-// parsing on each request and guarantees the dashboard can load the structured
-// data even if parsing fails later on.
-func preloadParsedCodes() {
-	for id, info := range codeFiles {
-		jsonPath := filepath.Join(rootDir, "dashbord-react", fmt.Sprintf("code_%s.json", id))
-		if _, err := os.Stat(jsonPath); err == nil {
-			// already generated
-			continue
+func getParsedCodeHandler(c *gin.Context) {
+	id := c.Param("id")
+	if date := c.Query("date"); date != "" {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "date must be YYYY-MM-DD"})
+			return
 		}
-		pc, err := parseCodeFile(info.path, id, info.title)
+		pc, v, err := loadParsedCodeAt(id, date)
 		if err != nil {
-			fmt.Println("failed to parse", id, "-", err)
-			continue
-		}
-		pc.LastUpdated = time.Now().Format(time.RFC3339)
-		if data, err := json.MarshalIndent(pc, "", "  "); err == nil {
-			_ = os.WriteFile(jsonPath, data, 0644)
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
 		}
+		c.Header("X-Code-Version", v.Date)
+		c.JSON(http.StatusOK, pc)
+		return
+	}
+	// loadParsedCode waits for the startup parsing and replaces a stored
+	// file written by another version of the parser
+	pc, err := loadParsedCode(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "code not found"})
+		return
+	}
+	if notModified(c, id) {
+		return
 	}
-}
-
-func getParsedCodeHandler(c *gin.Context) {
-	id := c.Param("id")
 	jsonPath := filepath.Join(rootDir, "dashbord-react", fmt.Sprintf("code_%s.json", id))
 	if _, err := os.Stat(jsonPath); err == nil {
 		c.File(jsonPath)
 		return
 	}
-	pc, err := loadParsedCode(id)
+	c.JSON(http.StatusOK, pc)
+}
+
+// getArticleHandler returns a single article looked up by its number, in any
+// of the forms used by the codes ("86^1", "86¹", "86 bis", "2.663").
+func getArticleHandler(c *gin.Context) {
+	if notModified(c, c.Param("id")) {
+		return
+	}
+	pc, err := loadParsedCode(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "code not found"})
 		return
 	}
-	c.JSON(http.StatusOK, pc)
+	art := findArticle(pc, c.Param("number"))
+	if art == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "article not found"})
+		return
+	}
+	c.JSON(http.StatusOK, art)
 }
 
+// saveParsedCodeHandler stores an edited parsed code. With ?text=true the
+// edited articles are also written over their lines in the source text,
+// provided parsing the new text gives back the same code; otherwise nothing
+// is saved and the reason or the fields that would change are returned. The
+// text replaced is kept as a dated version of the code.
 func saveParsedCodeHandler(c *gin.Context) {
 	id := c.Param("id")
 	var pc ParsedCode
@@ -327,7 +361,45 @@ func saveParsedCodeHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
 		return
 	}
+	kept := ""
+	if c.Query("text") == "true" {
+		info, ok := codeFiles[id]
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "code not found"})
+			return
+		}
+		g, err := grammarFor(id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		data, err := os.ReadFile(info.path)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		// the article list and the analysis results follow from the tree
+		collectArticles(&pc)
+		analyzeParsedCode(&pc)
+		lines, err := spliceArticles(strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"), &pc, g)
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		if diffs := parseDiff(&pc, lines, g, 20); len(diffs) > 0 {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "code cannot be written as text without changes", "differences": diffs})
+			return
+		}
+		if kept, err = replaceCodeText(id, []byte(strings.Join(lines, "\n")+"\n")); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
 	cacheAdd(id, &pc)
+	invalidateCitationGraph()
+	invalidateGlossary()
+	reindexCode(id, &pc)
+	invalidateSuggestions(id)
 	jsonPath := filepath.Join(rootDir, "dashbord-react", fmt.Sprintf("code_%s.json", id))
 	if data, err := json.MarshalIndent(pc, "", "  "); err == nil {
 		if err := os.WriteFile(jsonPath, data, 0644); err != nil {
@@ -335,6 +407,10 @@ func saveParsedCodeHandler(c *gin.Context) {
 			return
 		}
 	}
+	if kept != "" {
+		c.JSON(http.StatusOK, gin.H{"previousVersion": kept})
+		return
+	}
 	c.Status(http.StatusOK)
 }
 
@@ -443,11 +519,12 @@ func loadParsedCode(id string) (*ParsedCode, error) {
 	if pc, ok := cacheGet(id); ok {
 		return pc, nil
 	}
+	waitForPreload(id)
 
 	jsonPath := filepath.Join(rootDir, "dashbord-react", fmt.Sprintf("code_%s.json", id))
 	if data, err := os.ReadFile(jsonPath); err == nil {
 		var pc ParsedCode
-		if json.Unmarshal(data, &pc) == nil {
+		if json.Unmarshal(data, &pc) == nil && pc.ParserVersion == parserVersion {
 			cacheAdd(id, &pc)
 			return &pc, nil
 		}
@@ -458,10 +535,15 @@ func loadParsedCode(id string) (*ParsedCode, error) {
 		return nil, fmt.Errorf("unknown code id")
 	}
 
-	pc, err := parseCodeFile(info.path, id, info.title)
+	source, err := statSource(info.path)
 	if err != nil {
 		return nil, err
 	}
+	pc, err := buildParsedCode(info.path, id, info.title)
+	if err != nil {
+		return nil, err
+	}
+	storeDiagnostics(id, source, pc.Diagnostics)
 	pc.LastUpdated = time.Now().Format(time.RFC3339)
 	cacheAdd(id, pc)
 
@@ -1394,12 +1476,17 @@ func toggleArticlePrefsHandler(kind string) gin.HandlerFunc {
 			return
 		}
 		var payload struct {
-			ID string `json:"id"`
+			ID   string `json:"id"`
+			Code string `json:"code"`
 		}
 		if err := c.BindJSON(&payload); err != nil || payload.ID == "" {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
 			return
 		}
+		// accept positional IDs from older clients
+		if id, ok := resolveArticleID(payload.ID, payload.Code); ok {
+			payload.ID = id
+		}
 		mu.Lock()
 		prefs, ok := userArticlePrefs[user.ID]
 		if !ok {
@@ -1567,12 +1654,17 @@ func getFollowingHandler(c *gin.Context) {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		os.Exit(runDiffCommand(os.Args[2:]))
+	}
 	fmt.Println("Using repository root:", rootDir)
 	ensureDataDir()
 	loadUsers()
 	loadTokens()
 	loadCodes()
+	loadCodeRegistry()
 	loadArticlePrefs()
+	migrateArticlePrefs()
 	preloadParsedCodes()
 	r := gin.Default()
 
@@ -1586,6 +1678,7 @@ func main() {
 		}
 		c.Next()
 	})
+	r.Use(compressMiddleware)
 
 	api := r.Group("/api")
 	{
@@ -1604,11 +1697,36 @@ func main() {
 		api.POST("/profile/avatar", uploadAvatar)
 		api.GET("/files", listFiles)
 		api.GET("/codes", listCodes)
+		api.GET("/codes/status", codesStatusHandler)
 		api.GET("/codes/:id", getCode)
+		api.GET("/codes/:id/amendments", getAmendmentsHandler)
+		api.GET("/codes/:id/versions", listCodeVersionsHandler)
+		api.GET("/codes/:id/diff", getCodeDiffHandler)
+		api.GET("/codes/:id/changes", getCodeChangesHandler)
+		api.GET("/codes/:id/suggest", suggestHandler)
+		api.POST("/codes/:id/versions", uploadCodeVersionHandler)
+		api.POST("/import/html", importCodeHandler(htmlToLines))
+		api.POST("/import/docx", importCodeHandler(docxToLines))
+		api.GET("/codes/:id/outline", getOutlineHandler)
+		api.GET("/codes/:id/nodes/:node", getCodeNodeHandler)
+		api.GET("/codes/:id/articles", listArticlesHandler)
+		api.GET("/codes/:id/articles/:number", getArticleHandler)
+		api.GET("/codes/:id/articles/:number/references", getArticleReferencesHandler)
+		api.GET("/codes/:id/articles/:number/related", getRelatedArticlesHandler)
+		api.GET("/codes/:id/articles/:number/overrides", articleOverrideHandler)
+		api.PUT("/codes/:id/articles/:number/overrides", articleOverrideHandler)
+		api.DELETE("/codes/:id/articles/:number/overrides", articleOverrideHandler)
+		api.GET("/article-ids/resolve", resolveArticleIDHandler)
+		api.GET("/decisions", listDecisionsHandler)
+		api.GET("/glossary", glossaryHandler)
+		api.GET("/search", searchHandler)
+		api.GET("/resolve", resolveCitationHandler)
 		api.GET("/code-text/:id", getCodeTextHandler)
 		api.GET("/code-text-json/:id", getCodeTextJSON)
+		api.GET("/code-diagnostics/:id", getCodeDiagnosticsHandler)
 		api.POST("/save-code-text/:id", saveCodeTextJSON)
 		api.GET("/parsed-code/:id", getParsedCodeHandler)
+		api.GET("/parsed-code/:id/text", getSerializedCodeHandler)
 		api.POST("/save-parsed-code/:id", saveParsedCodeHandler)
 
 		api.GET("/utils", getUtilsHandler)
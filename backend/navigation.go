@@ -0,0 +1,288 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// OutlineNode is a heading of a code without the text of its articles.
+// Kind is "part", "book", "title", "chapter", "section" or "subsection";
+// the article range and count cover every article beneath the heading.
+type OutlineNode struct {
+	ID           string        `json:"id"`
+	Kind         string        `json:"kind"`
+	Title        string        `json:"title"`
+	Subtitle     string        `json:"subtitle,omitempty"`
+	Label        string        `json:"label,omitempty"`
+	Number       string        `json:"number,omitempty"`
+	FirstArticle string        `json:"firstArticle,omitempty"`
+	LastArticle  string        `json:"lastArticle,omitempty"`
+	ArticleCount int           `json:"articleCount"`
+	Articles     []ArticleStub `json:"articles,omitempty"`
+	Children     []OutlineNode `json:"children,omitempty"`
+}
+
+// ArticleStub names an article listed in an outline.
+type ArticleStub struct {
+	ID       string `json:"id"`
+	Number   string `json:"number"`
+	Title    string `json:"title"`
+	Repealed bool   `json:"repealed,omitempty"`
+}
+
+// navNode is a heading of the flattened hierarchy of a code, linked to its
+// parent and children by their index.
+type navNode struct {
+	OutlineNode
+	parent   int
+	children []int
+	articles []*Article // the articles right under the heading
+}
+
+// codeNavigation flattens the hierarchy of a code into its headings in
+// document order; the index maps the heading IDs to their position.
+func codeNavigation(code *ParsedCode) ([]navNode, map[string]int) {
+	var nodes []navNode
+	var stack []int
+	enter := func(depth int, kind, id, title, subtitle, label, number string) {
+		if len(stack) > depth {
+			stack = stack[:depth]
+		}
+		parent := -1
+		if depth > 0 {
+			parent = stack[depth-1]
+			nodes[parent].children = append(nodes[parent].children, len(nodes))
+		}
+		nodes = append(nodes, navNode{
+			OutlineNode: OutlineNode{ID: id, Kind: kind, Title: title, Subtitle: subtitle, Label: label, Number: number},
+			parent:      parent,
+		})
+		stack = append(stack, len(nodes)-1)
+	}
+	articles := func(list []Article) {
+		for i := range list {
+			a := &list[i]
+			last := stack[len(stack)-1]
+			nodes[last].articles = append(nodes[last].articles, a)
+			for _, n := range stack {
+				if nodes[n].FirstArticle == "" {
+					nodes[n].FirstArticle = a.Number
+				}
+				nodes[n].LastArticle = a.Number
+				nodes[n].ArticleCount++
+			}
+		}
+	}
+	book := func(depth int, b *Book) {
+		if b.Title == "" && b.Part != "" {
+			// titles written right under a part are nested in the part
+			depth--
+		} else {
+			enter(depth, "book", b.ID, b.Title, b.Subtitle, b.Label, b.Number)
+		}
+		for j := range b.Titles {
+			t := &b.Titles[j]
+			enter(depth+1, "title", t.ID, t.Title, t.Subtitle, t.Label, t.Number)
+			for k := range t.Chapters {
+				ch := &t.Chapters[k]
+				enter(depth+2, "chapter", ch.ID, ch.Title, ch.Subtitle, ch.Label, ch.Number)
+				for l := range ch.Sections {
+					sec := &ch.Sections[l]
+					enter(depth+3, "section", sec.ID, sec.Title, sec.Subtitle, sec.Label, sec.Number)
+					articles(sec.Articles)
+					for m := range sec.Subsections {
+						sub := &sec.Subsections[m]
+						enter(depth+4, "subsection", sub.ID, sub.Title, sub.Subtitle, sub.Label, sub.Number)
+						articles(sub.Articles)
+					}
+				}
+			}
+		}
+	}
+	walkParts(code, func(p *Part) {
+		enter(0, "part", p.ID, p.Title, p.Subtitle, p.Label, p.Number)
+	}, func(b *Book) {
+		if b.Part != "" {
+			book(1, b)
+		} else {
+			book(0, b)
+		}
+	})
+	index := make(map[string]int, len(nodes))
+	for i := range nodes {
+		index[nodes[i].ID] = i
+	}
+	return nodes, index
+}
+
+func articleStubs(list []*Article) []ArticleStub {
+	stubs := make([]ArticleStub, 0, len(list))
+	for _, a := range list {
+		stubs = append(stubs, ArticleStub{ID: a.ID, Number: a.Number, Title: a.Title, Repealed: a.Repealed})
+	}
+	return stubs
+}
+
+// outlineTree nests the headings below parent (-1 for the top level) down to
+// depth levels, 0 meaning all of them. withArticles lists the articles right
+// under each heading.
+func outlineTree(nodes []navNode, parent, depth int, withArticles bool) []OutlineNode {
+	var ids []int
+	if parent < 0 {
+		for i := range nodes {
+			if nodes[i].parent < 0 {
+				ids = append(ids, i)
+			}
+		}
+	} else {
+		ids = nodes[parent].children
+	}
+	out := []OutlineNode{}
+	for _, i := range ids {
+		n := nodes[i].OutlineNode
+		if withArticles && len(nodes[i].articles) > 0 {
+			n.Articles = articleStubs(nodes[i].articles)
+		}
+		if depth != 1 && len(nodes[i].children) > 0 {
+			next := depth - 1
+			if depth == 0 {
+				next = 0
+			}
+			n.Children = outlineTree(nodes, i, next, withArticles)
+		}
+		out = append(out, n)
+	}
+	return out
+}
+
+// getOutlineHandler returns the headings of a code without the articles, so
+// that a client can show the table of contents before loading any text.
+// depth limits the levels returned and articles=true adds the number and
+// title of the articles under each heading.
+func getOutlineHandler(c *gin.Context) {
+	if notModified(c, c.Param("id")) {
+		return
+	}
+	pc, err := loadParsedCode(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "code not found"})
+		return
+	}
+	depth := 0
+	if d := c.Query("depth"); d != "" {
+		if depth, err = strconv.Atoi(d); err != nil || depth < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "depth must be a number of levels"})
+			return
+		}
+	}
+	nodes, _ := codeNavigation(pc)
+	c.JSON(http.StatusOK, gin.H{
+		"id":            pc.ID,
+		"title":         pc.Title,
+		"totalArticles": pc.TotalArticles,
+		"outline":       outlineTree(nodes, -1, depth, c.Query("articles") == "true"),
+	})
+}
+
+// getCodeNodeHandler returns one heading of a code by ID with the headings
+// leading to it, its child headings (without their own children) and the
+// full articles placed directly under it.
+func getCodeNodeHandler(c *gin.Context) {
+	if notModified(c, c.Param("id")) {
+		return
+	}
+	pc, err := loadParsedCode(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "code not found"})
+		return
+	}
+	nodes, index := codeNavigation(pc)
+	i, ok := index[c.Param("node")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "node not found"})
+		return
+	}
+	path := []OutlineNode{}
+	for p := nodes[i].parent; p >= 0; p = nodes[p].parent {
+		path = append([]OutlineNode{nodes[p].OutlineNode}, path...)
+	}
+	articles := make([]Article, 0, len(nodes[i].articles))
+	for _, a := range nodes[i].articles {
+		articles = append(articles, *a)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"node":     nodes[i].OutlineNode,
+		"path":     path,
+		"children": outlineTree(nodes, i, 1, false),
+		"articles": articles,
+	})
+}
+
+// default and largest page of listArticlesHandler
+const (
+	articlePageSize    = 50
+	maxArticlePageSize = 200
+)
+
+// listArticlesHandler pages through the articles of a code in document
+// order. from and to restrict the list to a range of article numbers
+// ("1-100", inserted articles included); offset and limit select the page.
+func listArticlesHandler(c *gin.Context) {
+	if notModified(c, c.Param("id")) {
+		return
+	}
+	pc, err := loadParsedCode(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "code not found"})
+		return
+	}
+	from, to := 0, -1
+	for _, bound := range []struct {
+		value string
+		key   *int
+	}{{c.Query("from"), &from}, {c.Query("to"), &to}} {
+		if bound.value == "" {
+			continue
+		}
+		if !articleNumberRe.MatchString(bound.value) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be article numbers"})
+			return
+		}
+		*bound.key = articleSortKey(bound.value)
+	}
+	// "to=100" takes in 100^1, 100^2 ...
+	if t := c.Query("to"); t != "" && !strings.Contains(normalizeArticleNumber(t), "^") {
+		to += 999
+	}
+	offset, limit := 0, articlePageSize
+	if n, err := strconv.Atoi(c.Query("offset")); err == nil && n > 0 {
+		offset = n
+	}
+	if n, err := strconv.Atoi(c.Query("limit")); err == nil && n > 0 && n <= maxArticlePageSize {
+		limit = n
+	}
+
+	var selected []Article
+	for _, a := range pc.Articles {
+		key := articleSortKey(a.Number)
+		if key >= from && (to < 0 || key <= to) {
+			selected = append(selected, a)
+		}
+	}
+	total := len(selected)
+	page := []Article{}
+	if offset < total {
+		end := offset + limit
+		if end > total {
+			end = total
+		}
+		page = selected[offset:end]
+	}
+	out := gin.H{"total": total, "offset": offset, "limit": limit, "articles": page}
+	if offset+limit < total {
+		out["nextOffset"] = offset + limit
+	}
+	c.JSON(http.StatusOK, out)
+}
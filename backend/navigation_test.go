@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const navigationCodeText = `Cartea I Despre persoane
+Titlul I Dispoziții generale
+Capitolul I Principii
+Articolul 1
+Obiectul
+(1) Prezentul cod reglementează raporturile dintre persoane.
+Articolul 2
+Izvoarele
+(1) Izvoarele dreptului sunt legea și uzanțele.
+Capitolul II Aplicarea legii
+Secțiunea 1 Aplicarea în timp
+Articolul 3
+Neretroactivitatea
+(1) Legea se aplică numai pentru viitor.
+Articolul 3^1
+Legea nouă
+(1) Legea nouă se aplică faptelor viitoare.
+Secțiunea a 2-a Aplicarea în spațiu
+Subsecțiunea 1 Persoanele
+Articolul 4
+Legea personală
+(1) Starea persoanei este cârmuită de legea sa națională.
+Titlul II Persoana fizică
+Capitolul I Capacitatea
+Articolul 5
+Abrogat.
+`
+
+// flattenOutline lists the nodes of an outline as "kind title first-last
+// count [articles]", indented by depth.
+func flattenOutline(nodes []OutlineNode, indent string) []string {
+	var out []string
+	for _, n := range nodes {
+		s := fmt.Sprintf("%s%s %s %s-%s %d", indent, n.Kind, n.Title, n.FirstArticle, n.LastArticle, n.ArticleCount)
+		for _, a := range n.Articles {
+			s += " " + a.Number
+			if a.Repealed {
+				s += "(repealed)"
+			}
+		}
+		out = append(out, s)
+		out = append(out, flattenOutline(n.Children, indent+"  ")...)
+	}
+	return out
+}
+
+func TestOutlineTree(t *testing.T) {
+	g, err := loadGrammar("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc := parseCodeLines(strings.Split(navigationCodeText, "\n"), "civil", "Codul Civil", g)
+	analyzeParsedCode(pc)
+	nodes, index := codeNavigation(pc)
+	if len(index) != len(nodes) || nodes[index["book_1_title_1_ch_2_sec_2_sub_1"]].Kind != "subsection" {
+		t.Errorf("index %v", index)
+	}
+
+	want := []string{
+		"book Cartea I 1-5 6",
+		"  title Titlul I 1-4 5",
+		"    chapter Capitolul I 1-2 2",
+		"      section  1-2 2 1 2",
+		"    chapter Capitolul II 3-4 3",
+		"      section Secțiunea 1 3-3^1 2 3 3^1",
+		"      section Secțiunea a 2-a 4-4 1",
+		"        subsection Subsecțiunea 1 4-4 1 4",
+		"  title Titlul II 5-5 1",
+		"    chapter Capitolul I 5-5 1",
+		"      section  5-5 1 5(repealed)",
+	}
+	if got := flattenOutline(outlineTree(nodes, -1, 0, true), ""); !reflect.DeepEqual(got, want) {
+		t.Errorf("outline\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	// depth counts the levels returned; articles are only listed on request
+	want = []string{"book Cartea I 1-5 6", "  title Titlul I 1-4 5", "  title Titlul II 5-5 1"}
+	if got := flattenOutline(outlineTree(nodes, -1, 2, false), ""); !reflect.DeepEqual(got, want) {
+		t.Errorf("outline of depth 2\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	want = []string{"section Secțiunea 1 3-3^1 2", "section Secțiunea a 2-a 4-4 1"}
+	if got := flattenOutline(outlineTree(nodes, index["book_1_title_1_ch_2"], 1, false), ""); !reflect.DeepEqual(got, want) {
+		t.Errorf("children of a chapter %q, want %q", got, want)
+	}
+}
+
+func navigationTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	useTestCode(t, navigationCodeText)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/codes/:id/outline", getOutlineHandler)
+	r.GET("/codes/:id/nodes/:node", getCodeNodeHandler)
+	r.GET("/codes/:id/articles", listArticlesHandler)
+	return r
+}
+
+func TestOutlineHandlers(t *testing.T) {
+	r := navigationTestRouter(t)
+	get := func(path string, out interface{}) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if out != nil && w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+				t.Fatalf("%s: %v", path, err)
+			}
+		}
+		return w.Code
+	}
+
+	var outline struct {
+		TotalArticles int           `json:"totalArticles"`
+		Outline       []OutlineNode `json:"outline"`
+	}
+	if status := get("/codes/test/outline?depth=1", &outline); status != http.StatusOK || outline.TotalArticles != 6 || len(outline.Outline) != 1 || outline.Outline[0].Children != nil {
+		t.Errorf("outline of depth 1: status %d, %+v", status, outline)
+	}
+	for _, path := range []string{"/codes/test/outline?depth=x", "/codes/test/outline?depth=-1"} {
+		if status := get(path, nil); status != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", path, status)
+		}
+	}
+
+	var node struct {
+		Node     OutlineNode   `json:"node"`
+		Path     []OutlineNode `json:"path"`
+		Children []OutlineNode `json:"children"`
+		Articles []Article     `json:"articles"`
+	}
+	if status := get("/codes/test/nodes/book_1_title_1_ch_2_sec_1", &node); status != http.StatusOK {
+		t.Fatalf("node: status %d", status)
+	}
+	var path []string
+	for _, n := range node.Path {
+		path = append(path, n.ID)
+	}
+	if node.Node.Subtitle != "Aplicarea în timp" || !reflect.DeepEqual(path, []string{"book_1", "book_1_title_1", "book_1_title_1_ch_2"}) ||
+		len(node.Children) != 0 || len(node.Articles) != 2 || node.Articles[1].Content != "(1) Legea nouă se aplică faptelor viitoare." {
+		t.Errorf("node %+v", node)
+	}
+	if status := get("/codes/test/nodes/book_1_title_1_ch_2", &node); status != http.StatusOK || len(node.Children) != 2 || node.Children[1].Children != nil || len(node.Articles) != 0 {
+		t.Errorf("chapter: status %d, %d children, %d articles", status, len(node.Children), len(node.Articles))
+	}
+	for _, path := range []string{"/codes/test/nodes/book_9", "/codes/nope/nodes/book_1", "/codes/nope/outline"} {
+		if status := get(path, nil); status != http.StatusNotFound {
+			t.Errorf("%s: status %d, want 404", path, status)
+		}
+	}
+}
+
+func TestListArticlesHandler(t *testing.T) {
+	r := navigationTestRouter(t)
+	tests := []struct {
+		query      string
+		status     int
+		numbers    string
+		total      int
+		nextOffset int
+	}{
+		{"", http.StatusOK, "1 2 3 3^1 4 5", 6, 0},
+		{"?limit=2", http.StatusOK, "1 2", 6, 2},
+		{"?offset=4&limit=2", http.StatusOK, "4 5", 6, 0},
+		{"?offset=10", http.StatusOK, "", 6, 0},
+		// a range takes in the articles inserted after its last number
+		{"?from=2&to=3", http.StatusOK, "2 3 3^1", 3, 0},
+		{"?from=3^1", http.StatusOK, "3^1 4 5", 3, 0},
+		{"?to=3^1", http.StatusOK, "1 2 3 3^1", 4, 0},
+		{"?from=x", http.StatusBadRequest, "", 0, 0},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/codes/test/articles"+tt.query, nil))
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.query, w.Code, tt.status)
+			continue
+		}
+		if w.Code != http.StatusOK {
+			continue
+		}
+		var out struct {
+			Total      int       `json:"total"`
+			NextOffset int       `json:"nextOffset"`
+			Articles   []Article `json:"articles"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil || out.Articles == nil {
+			t.Errorf("%s: %s", tt.query, w.Body)
+			continue
+		}
+		var numbers []string
+		for _, a := range out.Articles {
+			numbers = append(numbers, a.Number)
+		}
+		if got := strings.Join(numbers, " "); got != tt.numbers || out.Total != tt.total || out.NextOffset != tt.nextOffset {
+			t.Errorf("%s: %q, total %d, next %d, want %q, %d and %d", tt.query, got, out.Total, out.NextOffset, tt.numbers, tt.total, tt.nextOffset)
+		}
+	}
+}
@@ -5,25 +5,57 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
+// Letter is a lettered point ("a) ...") inside a paragraph.
+type Letter struct {
+	Letter string `json:"letter"`
+	Text   string `json:"text"`
+}
+
+// Paragraph is a numbered paragraph (alineat) of an article. Articles without
+// numbered paragraphs get a single paragraph with an empty Number. Closing is
+// the text following the letters ("pedeapsa este închisoarea ...").
+type Paragraph struct {
+	Number  string   `json:"number"`
+	Text    string   `json:"text"`
+	Letters []Letter `json:"letters,omitempty"`
+	Closing string   `json:"closing,omitempty"`
+	Notes   []string `json:"notes,omitempty"`
+}
+
 type Article struct {
-	ID          string   `json:"id"`
-	Number      string   `json:"number"`
-	Title       string   `json:"title"`
-	Content     string   `json:"content"`
-	Notes       []string `json:"notes"`
-	References  []string `json:"references"`
-	IsImportant bool     `json:"isImportant"`
-	Keywords    []string `json:"keywords"`
-	Order       int      `json:"order"`
+	ID            string           `json:"id"`
+	Number        string           `json:"number"`
+	SortKey       int              `json:"sortKey"`
+	Title         string           `json:"title"`
+	Content       string           `json:"content"`
+	Paragraphs    []Paragraph      `json:"paragraphs,omitempty"`
+	Notes         []string         `json:"notes"`
+	References    []string         `json:"references"`
+	Citations     []Citation       `json:"citations,omitempty"`
+	Amendments    []Amendment      `json:"amendments,omitempty"`
+	Decisions     []Decision       `json:"decisions,omitempty"`
+	Repealed      bool             `json:"repealed,omitempty"`
+	IsImportant   bool             `json:"isImportant"`
+	Keywords      []string         `json:"keywords"`
+	Importance    int              `json:"importance,omitempty"`
+	CitedBy       int              `json:"citedBy,omitempty"`
+	ExamQuestions int              `json:"examQuestions,omitempty"`
+	Related       []RelatedArticle `json:"related,omitempty"`
+	Order         int              `json:"order"`
 }
 
 type CodeSection struct {
 	ID          string        `json:"id"`
 	Title       string        `json:"title"`
 	Subtitle    string        `json:"subtitle,omitempty"`
+	Label       string        `json:"label,omitempty"`
+	Number      string        `json:"number,omitempty"`
 	Subsections []CodeSection `json:"subsections"`
 	Articles    []Article     `json:"articles"`
 	Order       int           `json:"order"`
@@ -33,6 +65,8 @@ type Chapter struct {
 	ID       string        `json:"id"`
 	Title    string        `json:"title"`
 	Subtitle string        `json:"subtitle,omitempty"`
+	Label    string        `json:"label,omitempty"`
+	Number   string        `json:"number,omitempty"`
 	Sections []CodeSection `json:"sections"`
 	Order    int           `json:"order"`
 }
@@ -41,27 +75,66 @@ type CodeTitle struct {
 	ID       string    `json:"id"`
 	Title    string    `json:"title"`
 	Subtitle string    `json:"subtitle,omitempty"`
+	Label    string    `json:"label,omitempty"`
+	Number   string    `json:"number,omitempty"`
 	Chapters []Chapter `json:"chapters"`
 	Order    int       `json:"order"`
 }
 
+// Part is a "Partea" heading above the books of a code (Codul penal). The
+// books stay in ParsedCode.Books and name their part in Book.Part, so that
+// clients reading only the books still get the whole code. Titles written
+// right under a part are kept in a book of the part without a title.
+type Part struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Subtitle string `json:"subtitle,omitempty"`
+	Label    string `json:"label,omitempty"`
+	Number   string `json:"number,omitempty"`
+	Order    int    `json:"order"`
+}
+
 type Book struct {
 	ID       string      `json:"id"`
+	Part     string      `json:"part,omitempty"`
 	Title    string      `json:"title"`
 	Subtitle string      `json:"subtitle,omitempty"`
+	Label    string      `json:"label,omitempty"`
+	Number   string      `json:"number,omitempty"`
 	Titles   []CodeTitle `json:"titles"`
 	Order    int         `json:"order"`
 }
 
+var (
+	paragraphRe = regexp.MustCompile(`^\((\d+(?:\^\d+)?)\)\s*(.*)$`)
+	letterRe    = regexp.MustCompile(`^([a-zăâîșțşţ]{1,2}(?:\^\d+)?)\)\s+(.*)$`)
+	// the end of a letter followed by another one or by the closing text
+	letterEndRe = regexp.MustCompile(`[.;,](?:\s+(?:sau|și|ori))?$`)
+)
+
+// parserVersion is stored in every code_<id>.json and must be increased
+// whenever the parser, the analysis stages or the shape of ParsedCode change,
+// so that files written by an older build are parsed again.
+const parserVersion = 5
+
 type ParsedCode struct {
 	ID            string            `json:"id"`
 	Title         string            `json:"title"`
 	Type          string            `json:"type"`
+	Parts         []Part            `json:"parts,omitempty"`
 	Books         []Book            `json:"books"`
 	Metadata      map[string]string `json:"metadata"`
 	LastUpdated   string            `json:"lastUpdated"`
 	TotalArticles int               `json:"totalArticles"`
 	Articles      []Article         `json:"articles"`
+	ParserVersion int               `json:"parserVersion"`
+
+	TableOfContents *TableOfContents `json:"tableOfContents,omitempty"`
+	Diagnostics     []Diagnostic     `json:"-"`
+
+	// the 1-based line of the heading of each article, by ID, when the
+	// code was parsed from a text
+	articleLines map[string]int
 }
 
 func parseCodeFile(path, codeID, codeTitle string) (*ParsedCode, error) {
@@ -76,14 +149,28 @@ func parseCodeFile(path, codeID, codeTitle string) (*ParsedCode, error) {
 	buf := make([]byte, 0, 64*1024)
 	scanner.Buffer(buf, 1024*1024)
 
-	bookRe := regexp.MustCompile(`(?i)^Cartea`)
-	titleRe := regexp.MustCompile(`(?i)^Titlul`)
-	chapterRe := regexp.MustCompile(`(?i)^Capitolul`)
-	sectionRe := regexp.MustCompile(`(?i)^Sec[tțţ]iunea`)
-	subsectionRe := regexp.MustCompile(`(?i)^Subsec[tțţ]iunea`)
-	articleRe := regexp.MustCompile(`(?i)^Articolul\s+(\d+)\s*(?:-\s*(.+))?$`)
-	noteRe := regexp.MustCompile(`(?i)^Not[aă]`)
-	refRe := regexp.MustCompile(`(?i)(monitorul oficial|legea nr|ril nr|decizia)`)
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	g, err := grammarFor(codeID)
+	if err != nil {
+		return nil, err
+	}
+	return parseCodeLines(lines, codeID, codeTitle, g), nil
+}
+
+// parseCodeLines builds the code hierarchy from the raw lines of a code text,
+// recognising headings, articles and notes with the grammar g.
+// The table of contents at the top of the text, if any, is parsed separately
+// and checked against the body.
+func parseCodeLines(lines []string, codeID, codeTitle string, g *compiledGrammar) *ParsedCode {
+	partRe, bookRe, titleRe := g.levels["part"], g.levels["book"], g.levels["title"]
+	chapterRe, sectionRe, subsectionRe := g.levels["chapter"], g.levels["section"], g.levels["subsection"]
+	articleRe, noteRe, refRe := g.article, g.note, g.references
 
 	code := &ParsedCode{
 		ID:       codeID,
@@ -93,6 +180,7 @@ func parseCodeFile(path, codeID, codeTitle string) (*ParsedCode, error) {
 		Metadata: map[string]string{},
 	}
 
+	var currentPart *Part
 	var currentBook *Book
 	var currentTitle *CodeTitle
 	var currentChapter *Chapter
@@ -100,41 +188,94 @@ func parseCodeFile(path, codeID, codeTitle string) (*ParsedCode, error) {
 	var currentSubsection *CodeSection
 	var currentArticle *Article
 	var expectTitle bool
+	// the subtitle of the heading read last, until an article starts
+	var headingSubtitle *string
+	// the name of the heading read last, which wrapped lines continue
+	var headingName *string
 
-	var bookOrder, titleOrder, chapterOrder, sectionOrder, subsectionOrder, articleOrder int
+	var partOrder, bookOrder, titleOrder, chapterOrder, sectionOrder, subsectionOrder, articleOrder int
 
 	var collectingNote bool
 	var noteLines []string
 
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
+	ids := newArticleIDs(codeID)
+	articleLines := map[string]int{}
+
+	// placeholder nodes are expected for the levels an act does not use
+	placeholder := func(i int, level, title string) {
+		if g.uses(level) {
+			addDiagnostic(code, i+1, "warning", "no %s heading before this line, added placeholder %s %q", level, level, title)
+		}
+	}
+
+	// books belong to the current part when the code is split into parts
+	// (Codul penal)
+	addBook := func(b Book) *Book {
+		if currentPart != nil {
+			b.Part = currentPart.ID
+		}
+		code.Books = append(code.Books, b)
+		return &code.Books[len(code.Books)-1]
+	}
+
+	// the book opened for headings found before any "Cartea". Under a part
+	// the titles belong to the part directly (Codul penal), so the book is
+	// left unnamed and is not a missing heading.
+	defaultBook := func(i int) *Book {
+		bookOrder++
+		if currentPart != nil {
+			return addBook(Book{ID: fmt.Sprintf("book_%d", bookOrder), Order: bookOrder, Titles: []CodeTitle{}})
+		}
+		placeholder(i, "book", "Intro")
+		return addBook(Book{ID: fmt.Sprintf("book_%d", bookOrder), Title: "Intro", Order: bookOrder, Titles: []CodeTitle{}})
+	}
+
+	toc, tocLines := detectTableOfContents(lines)
+	code.TableOfContents = toc
+
+	for i, raw := range lines {
+		if tocLines[i] {
+			continue
+		}
+		line := strings.TrimSpace(raw)
 		if line == "" {
 			continue
 		}
+		if n := utf8.RuneCountInString(line); n > maxLineLength {
+			addDiagnostic(code, i+1, "info", "very long line (%d characters), paragraphs may be merged", n)
+		}
 
 	ProcessLine:
 		if collectingNote {
 			if noteRe.MatchString(line) {
 				if len(noteLines) > 0 && currentArticle != nil {
-					currentArticle.Notes = append(currentArticle.Notes, strings.Join(noteLines, "\n"))
+					attachNote(currentArticle, strings.Join(noteLines, "\n"))
 				}
 				if currentArticle != nil {
 					noteLines = []string{line}
 					continue
 				}
 				// discard notes encountered outside any article
+				addDiagnostic(code, i+1, "warning", "note outside any article ignored")
 				noteLines = nil
 				collectingNote = false
 				continue
 			}
-			if bookRe.MatchString(line) || titleRe.MatchString(line) || chapterRe.MatchString(line) || sectionRe.MatchString(line) || subsectionRe.MatchString(line) || articleRe.MatchString(line) {
+			if g.isHeading(line) {
 				if len(noteLines) > 0 && currentArticle != nil {
-					currentArticle.Notes = append(currentArticle.Notes, strings.Join(noteLines, "\n"))
+					attachNote(currentArticle, strings.Join(noteLines, "\n"))
 				}
 				noteLines = nil
 				collectingNote = false
 				goto ProcessLine
 			}
+			if currentArticle != nil && paragraphRe.MatchString(line) {
+				// a numbered paragraph ends a note placed between paragraphs
+				attachNote(currentArticle, strings.Join(noteLines, "\n"))
+				noteLines = nil
+				collectingNote = false
+				goto ProcessLine
+			}
 			if currentArticle != nil {
 				noteLines = append(noteLines, line)
 			}
@@ -142,6 +283,23 @@ func parseCodeFile(path, codeID, codeTitle string) (*ParsedCode, error) {
 		}
 
 		switch {
+		case partRe.MatchString(line):
+			if currentArticle != nil {
+				if currentSubsection != nil {
+					currentSubsection.Articles = append(currentSubsection.Articles, *currentArticle)
+				} else if currentSection != nil {
+					currentSection.Articles = append(currentSection.Articles, *currentArticle)
+				}
+				currentArticle = nil
+			}
+			titleOrder, chapterOrder, sectionOrder, subsectionOrder, articleOrder = 0, 0, 0, 0, 0
+			partOrder++
+			h := splitHeading(line)
+			code.Parts = append(code.Parts, Part{ID: fmt.Sprintf("part_%d", partOrder), Title: h.title, Subtitle: h.subtitle, Label: h.label, Number: h.number, Order: partOrder})
+			currentPart = &code.Parts[len(code.Parts)-1]
+			headingSubtitle = subtitleOf(h, &currentPart.Subtitle)
+			headingName = nameOf(h, &currentPart.Title, &currentPart.Subtitle)
+			currentBook, currentTitle, currentChapter, currentSection, currentSubsection = nil, nil, nil, nil, nil
 		case bookRe.MatchString(line):
 			if currentArticle != nil {
 				if currentSubsection != nil {
@@ -153,9 +311,11 @@ func parseCodeFile(path, codeID, codeTitle string) (*ParsedCode, error) {
 			}
 			titleOrder, chapterOrder, sectionOrder, subsectionOrder, articleOrder = 0, 0, 0, 0, 0
 			bookOrder++
-			b := Book{ID: fmt.Sprintf("book_%d", bookOrder), Title: line, Order: bookOrder, Titles: []CodeTitle{}}
-			code.Books = append(code.Books, b)
-			currentBook = &code.Books[len(code.Books)-1]
+			h := splitHeading(line)
+			currentBook = addBook(Book{ID: fmt.Sprintf("book_%d", bookOrder), Title: h.title, Subtitle: h.subtitle, Label: h.label, Number: h.number, Order: bookOrder, Titles: []CodeTitle{}})
+			headingSubtitle = subtitleOf(h, &currentBook.Subtitle)
+			headingName = nameOf(h, &currentBook.Title, &currentBook.Subtitle)
+			currentTitle, currentChapter, currentSection, currentSubsection = nil, nil, nil, nil
 		case titleRe.MatchString(line):
 			if currentArticle != nil {
 				if currentSubsection != nil {
@@ -167,15 +327,17 @@ func parseCodeFile(path, codeID, codeTitle string) (*ParsedCode, error) {
 			}
 			chapterOrder, sectionOrder, subsectionOrder, articleOrder = 0, 0, 0, 0
 			titleOrder++
-			t := CodeTitle{ID: fmt.Sprintf("book_%d_title_%d", bookOrder, titleOrder), Title: line, Order: titleOrder, Chapters: []Chapter{}}
+			h := splitHeading(line)
 			if currentBook == nil {
 				// create default book if none exists
-				bookOrder++
-				code.Books = append(code.Books, Book{ID: fmt.Sprintf("book_%d", bookOrder), Title: "Intro", Order: bookOrder, Titles: []CodeTitle{}})
-				currentBook = &code.Books[len(code.Books)-1]
+				currentBook = defaultBook(i)
 			}
+			t := CodeTitle{ID: fmt.Sprintf("book_%d_title_%d", bookOrder, titleOrder), Title: h.title, Subtitle: h.subtitle, Label: h.label, Number: h.number, Order: titleOrder, Chapters: []Chapter{}}
 			currentBook.Titles = append(currentBook.Titles, t)
 			currentTitle = &currentBook.Titles[len(currentBook.Titles)-1]
+			currentChapter, currentSection, currentSubsection = nil, nil, nil
+			headingSubtitle = subtitleOf(h, &currentTitle.Subtitle)
+			headingName = nameOf(h, &currentTitle.Title, &currentTitle.Subtitle)
 		case chapterRe.MatchString(line):
 			if currentArticle != nil {
 				if currentSubsection != nil {
@@ -187,20 +349,23 @@ func parseCodeFile(path, codeID, codeTitle string) (*ParsedCode, error) {
 			}
 			sectionOrder, subsectionOrder, articleOrder = 0, 0, 0
 			chapterOrder++
-			ch := Chapter{ID: fmt.Sprintf("book_%d_title_%d_ch_%d", bookOrder, titleOrder, chapterOrder), Title: line, Order: chapterOrder, Sections: []CodeSection{}}
+			h := splitHeading(line)
 			if currentTitle == nil {
 				// create default title
 				titleOrder++
 				if currentBook == nil {
-					bookOrder++
-					code.Books = append(code.Books, Book{ID: fmt.Sprintf("book_%d", bookOrder), Title: "Intro", Order: bookOrder, Titles: []CodeTitle{}})
-					currentBook = &code.Books[len(code.Books)-1]
+					currentBook = defaultBook(i)
 				}
 				currentBook.Titles = append(currentBook.Titles, CodeTitle{ID: fmt.Sprintf("book_%d_title_%d", bookOrder, titleOrder), Title: "Untitled", Order: titleOrder, Chapters: []Chapter{}})
+				placeholder(i, "title", "Untitled")
 				currentTitle = &currentBook.Titles[len(currentBook.Titles)-1]
 			}
+			ch := Chapter{ID: fmt.Sprintf("book_%d_title_%d_ch_%d", bookOrder, titleOrder, chapterOrder), Title: h.title, Subtitle: h.subtitle, Label: h.label, Number: h.number, Order: chapterOrder, Sections: []CodeSection{}}
 			currentTitle.Chapters = append(currentTitle.Chapters, ch)
 			currentChapter = &currentTitle.Chapters[len(currentTitle.Chapters)-1]
+			currentSection, currentSubsection = nil, nil
+			headingSubtitle = subtitleOf(h, &currentChapter.Subtitle)
+			headingName = nameOf(h, &currentChapter.Title, &currentChapter.Subtitle)
 		case sectionRe.MatchString(line):
 			if currentArticle != nil {
 				if currentSubsection != nil {
@@ -213,26 +378,29 @@ func parseCodeFile(path, codeID, codeTitle string) (*ParsedCode, error) {
 			articleOrder = 0
 			subsectionOrder = 0
 			sectionOrder++
-			sec := CodeSection{ID: fmt.Sprintf("book_%d_title_%d_ch_%d_sec_%d", bookOrder, titleOrder, chapterOrder, sectionOrder), Title: line, Order: sectionOrder, Subsections: []CodeSection{}, Articles: []Article{}}
+			h := splitHeading(line)
 			if currentChapter == nil {
 				// create default chapter
 				chapterOrder++
 				if currentTitle == nil {
 					titleOrder++
 					if currentBook == nil {
-						bookOrder++
-						code.Books = append(code.Books, Book{ID: fmt.Sprintf("book_%d", bookOrder), Title: "Intro", Order: bookOrder, Titles: []CodeTitle{}})
-						currentBook = &code.Books[len(code.Books)-1]
+						currentBook = defaultBook(i)
 					}
 					currentBook.Titles = append(currentBook.Titles, CodeTitle{ID: fmt.Sprintf("book_%d_title_%d", bookOrder, titleOrder), Title: "Untitled", Order: titleOrder, Chapters: []Chapter{}})
+					placeholder(i, "title", "Untitled")
 					currentTitle = &currentBook.Titles[len(currentBook.Titles)-1]
 				}
 				currentTitle.Chapters = append(currentTitle.Chapters, Chapter{ID: fmt.Sprintf("book_%d_title_%d_ch_%d", bookOrder, titleOrder, chapterOrder), Title: "Unnamed", Order: chapterOrder, Sections: []CodeSection{}})
+				placeholder(i, "chapter", "Unnamed")
 				currentChapter = &currentTitle.Chapters[len(currentTitle.Chapters)-1]
 			}
+			sec := CodeSection{ID: fmt.Sprintf("book_%d_title_%d_ch_%d_sec_%d", bookOrder, titleOrder, chapterOrder, sectionOrder), Title: h.title, Subtitle: h.subtitle, Label: h.label, Number: h.number, Order: sectionOrder, Subsections: []CodeSection{}, Articles: []Article{}}
 			currentChapter.Sections = append(currentChapter.Sections, sec)
 			currentSection = &currentChapter.Sections[len(currentChapter.Sections)-1]
 			currentSubsection = nil
+			headingSubtitle = subtitleOf(h, &currentSection.Subtitle)
+			headingName = nameOf(h, &currentSection.Title, &currentSection.Subtitle)
 		case subsectionRe.MatchString(line):
 			if currentArticle != nil {
 				if currentSubsection != nil {
@@ -244,7 +412,7 @@ func parseCodeFile(path, codeID, codeTitle string) (*ParsedCode, error) {
 			}
 			articleOrder = 0
 			subsectionOrder++
-			sub := CodeSection{ID: fmt.Sprintf("book_%d_title_%d_ch_%d_sec_%d_sub_%d", bookOrder, titleOrder, chapterOrder, sectionOrder, subsectionOrder), Title: line, Order: subsectionOrder, Subsections: []CodeSection{}, Articles: []Article{}}
+			h := splitHeading(line)
 			if currentSection == nil {
 				// create a default section
 				sectionOrder++
@@ -253,22 +421,25 @@ func parseCodeFile(path, codeID, codeTitle string) (*ParsedCode, error) {
 					if currentTitle == nil {
 						titleOrder++
 						if currentBook == nil {
-							bookOrder++
-							code.Books = append(code.Books, Book{ID: fmt.Sprintf("book_%d", bookOrder), Title: "Intro", Order: bookOrder, Titles: []CodeTitle{}})
-							currentBook = &code.Books[len(code.Books)-1]
+							currentBook = defaultBook(i)
 						}
 						currentBook.Titles = append(currentBook.Titles, CodeTitle{ID: fmt.Sprintf("book_%d_title_%d", bookOrder, titleOrder), Title: "Untitled", Order: titleOrder, Chapters: []Chapter{}})
+						placeholder(i, "title", "Untitled")
 						currentTitle = &currentBook.Titles[len(currentBook.Titles)-1]
 					}
 					currentTitle.Chapters = append(currentTitle.Chapters, Chapter{ID: fmt.Sprintf("book_%d_title_%d_ch_%d", bookOrder, titleOrder, chapterOrder), Title: "Unnamed", Order: chapterOrder, Sections: []CodeSection{}})
+					placeholder(i, "chapter", "Unnamed")
 					currentChapter = &currentTitle.Chapters[len(currentTitle.Chapters)-1]
 				}
 				sec := CodeSection{ID: fmt.Sprintf("book_%d_title_%d_ch_%d_sec_%d", bookOrder, titleOrder, chapterOrder, sectionOrder), Title: "", Order: sectionOrder, Subsections: []CodeSection{}, Articles: []Article{}}
 				currentChapter.Sections = append(currentChapter.Sections, sec)
 				currentSection = &currentChapter.Sections[len(currentChapter.Sections)-1]
 			}
+			sub := CodeSection{ID: fmt.Sprintf("book_%d_title_%d_ch_%d_sec_%d_sub_%d", bookOrder, titleOrder, chapterOrder, sectionOrder, subsectionOrder), Title: h.title, Subtitle: h.subtitle, Label: h.label, Number: h.number, Order: subsectionOrder, Subsections: []CodeSection{}, Articles: []Article{}}
 			currentSection.Subsections = append(currentSection.Subsections, sub)
 			currentSubsection = &currentSection.Subsections[len(currentSection.Subsections)-1]
+			headingSubtitle = subtitleOf(h, &currentSubsection.Subtitle)
+			headingName = nameOf(h, &currentSubsection.Title, &currentSubsection.Subtitle)
 		case articleRe.MatchString(line):
 			if currentArticle != nil {
 				if currentSubsection != nil {
@@ -287,40 +458,51 @@ func parseCodeFile(path, codeID, codeTitle string) (*ParsedCode, error) {
 						// ensure we have a title and book
 						titleOrder++
 						if currentBook == nil {
-							bookOrder++
-							code.Books = append(code.Books, Book{ID: fmt.Sprintf("book_%d", bookOrder), Title: "Intro", Order: bookOrder, Titles: []CodeTitle{}})
-							currentBook = &code.Books[len(code.Books)-1]
+							currentBook = defaultBook(i)
 						}
 						currentBook.Titles = append(currentBook.Titles, CodeTitle{ID: fmt.Sprintf("book_%d_title_%d", bookOrder, titleOrder), Title: "Untitled", Order: titleOrder, Chapters: []Chapter{}})
+						placeholder(i, "title", "Untitled")
 						currentTitle = &currentBook.Titles[len(currentBook.Titles)-1]
 					}
 					currentTitle.Chapters = append(currentTitle.Chapters, Chapter{ID: fmt.Sprintf("book_%d_title_%d_ch_%d", bookOrder, titleOrder, chapterOrder), Title: "Unnamed", Order: chapterOrder, Sections: []CodeSection{}})
+					placeholder(i, "chapter", "Unnamed")
 					currentChapter = &currentTitle.Chapters[len(currentTitle.Chapters)-1]
 				}
 				sec := CodeSection{ID: fmt.Sprintf("book_%d_title_%d_ch_%d_sec_%d", bookOrder, titleOrder, chapterOrder, sectionOrder), Title: "", Order: sectionOrder, Subsections: []CodeSection{}, Articles: []Article{}}
 				currentChapter.Sections = append(currentChapter.Sections, sec)
 				currentSection = &currentChapter.Sections[len(currentChapter.Sections)-1]
 			}
+			headingSubtitle, headingName = nil, nil
 			articleOrder++
-			matches := articleRe.FindStringSubmatch(line)
-			num := ""
-			title := ""
-			if len(matches) > 1 {
-				num = matches[1]
-			}
-			if len(matches) > 2 {
-				title = matches[2]
+			num, title, content := g.articleHeading(line)
+			num = normalizeArticleNumber(num)
+			currentArticle = &Article{ID: ids.next(num), Number: num, SortKey: articleSortKey(num), Title: title, Order: articleOrder}
+			articleLines[currentArticle.ID] = i + 1
+			expectTitle = title == "" && content == ""
+			if content != "" {
+				// laws often start the text on the heading line ("Art. 1. - (1) ...")
+				currentArticle.Content = content
+				appendParagraphLine(currentArticle, content)
 			}
-			if currentSubsection != nil {
-				currentArticle = &Article{ID: fmt.Sprintf("book_%d_title_%d_ch_%d_sec_%d_sub_%d_art_%d", bookOrder, titleOrder, chapterOrder, sectionOrder, subsectionOrder, articleOrder), Number: num, Title: title, Order: articleOrder}
-			} else {
-				currentArticle = &Article{ID: fmt.Sprintf("book_%d_title_%d_ch_%d_sec_%d_art_%d", bookOrder, titleOrder, chapterOrder, sectionOrder, articleOrder), Number: num, Title: title, Order: articleOrder}
-			}
-			expectTitle = title == ""
 		case noteRe.MatchString(line):
+			headingSubtitle, headingName = nil, nil
+			if currentArticle == nil {
+				addDiagnostic(code, i+1, "warning", "note outside any article ignored")
+			}
 			collectingNote = true
 			noteLines = []string{line}
 			continue
+		case headingName != nil && *headingName != "" && continuesHeading(line):
+			// a heading name wrapped over several lines ("Capitolul IV
+			// Executarea silită a altor obligații de a face sau a
+			// obligațiilor", then "de a nu face")
+			*headingName += " " + line
+			headingSubtitle = nil
+		case headingSubtitle != nil && !strings.HasPrefix(line, "("):
+			// the name of a heading written on the next line ("Capitolul
+			// III", then "Ocrotirea majorului ...")
+			*headingSubtitle = line
+			headingName, headingSubtitle = headingSubtitle, nil
 		default:
 			if currentArticle != nil {
 				lower := strings.ToLower(line)
@@ -332,9 +514,10 @@ func parseCodeFile(path, codeID, codeTitle string) (*ParsedCode, error) {
 					} else {
 						currentArticle.Content = line
 					}
+					appendParagraphLine(currentArticle, line)
 				} else if refRe.MatchString(lower) {
 					currentArticle.References = append(currentArticle.References, line)
-				} else if expectTitle {
+				} else if expectTitle && !repealedContentRe.MatchString(line) {
 					currentArticle.Title = line
 					expectTitle = false
 				} else {
@@ -343,17 +526,14 @@ func parseCodeFile(path, codeID, codeTitle string) (*ParsedCode, error) {
 					} else {
 						currentArticle.Content = line
 					}
+					appendParagraphLine(currentArticle, line)
 				}
 			}
 		}
 	}
 
 	if collectingNote && len(noteLines) > 0 && currentArticle != nil {
-		currentArticle.Notes = append(currentArticle.Notes, strings.Join(noteLines, "\n"))
-	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, err
+		attachNote(currentArticle, strings.Join(noteLines, "\n"))
 	}
 
 	if currentArticle != nil {
@@ -364,22 +544,216 @@ func parseCodeFile(path, codeID, codeTitle string) (*ParsedCode, error) {
 		}
 	}
 
-	// gather all articles into code.Articles and count
-	var all []Article
+	collectArticles(code)
+	checkTableOfContents(code)
+	articleNumberingDiagnostics(code, articleLines)
+	code.articleLines = articleLines
+	return code
+}
+
+// subtitleOf returns where the line following a heading goes: the subtitle
+// of a numbered heading whose line holds no name. Lines after a named heading
+// ("§ 1. Intervenția voluntară", separators) are not part of it.
+func subtitleOf(h headingParts, subtitle *string) *string {
+	if h.label == "" || h.subtitle != "" {
+		return nil
+	}
+	return subtitle
+}
+
+// nameOf returns the field wrapped lines of a heading are added to: the
+// subtitle of a numbered heading, the whole title of any other.
+func nameOf(h headingParts, title, subtitle *string) *string {
+	if h.label == "" {
+		return title
+	}
+	return subtitle
+}
+
+// continuesHeading reports whether a line following a heading is the rest of
+// its name: it starts with a lowercase word, as names and articles never do,
+// and is not a letter of an enumeration.
+func continuesHeading(line string) bool {
+	r, _ := utf8.DecodeRuneInString(line)
+	return unicode.IsLower(r) && !letterRe.MatchString(line)
+}
+
+// buildParsedCode parses a code file and runs the analysis stages that enrich
+// the parsed structure, such as resolving in-text citations.
+func buildParsedCode(path, codeID, codeTitle string) (*ParsedCode, error) {
+	pc, err := parseCodeFile(path, codeID, codeTitle)
+	if err != nil {
+		return nil, err
+	}
+	analyzeParsedCode(pc)
+	pc.ParserVersion = parserVersion
+	return pc, nil
+}
+
+// analyzeParsedCode runs the analysis stages that enrich a freshly parsed code.
+func analyzeParsedCode(pc *ParsedCode) {
+	extractCitations(pc)
+	extractAmendments(pc)
+	extractDecisions(pc)
+	scoreArticles(pc)
+	relateArticles(pc)
+}
+
+// walkArticles calls fn for every article of the hierarchy in document order.
+// fn receives a pointer into the tree so it may modify the article in place;
+// call collectArticles afterwards to refresh code.Articles.
+func walkArticles(code *ParsedCode, fn func(*Article)) {
 	for i := range code.Books {
-		for j := range code.Books[i].Titles {
-			for k := range code.Books[i].Titles[j].Chapters {
-				for l := range code.Books[i].Titles[j].Chapters[k].Sections {
-					sec := code.Books[i].Titles[j].Chapters[k].Sections[l]
-					all = append(all, sec.Articles...)
+		b := &code.Books[i]
+		for j := range b.Titles {
+			for k := range b.Titles[j].Chapters {
+				for l := range b.Titles[j].Chapters[k].Sections {
+					sec := &b.Titles[j].Chapters[k].Sections[l]
+					for a := range sec.Articles {
+						fn(&sec.Articles[a])
+					}
 					for m := range sec.Subsections {
-						all = append(all, sec.Subsections[m].Articles...)
+						for a := range sec.Subsections[m].Articles {
+							fn(&sec.Subsections[m].Articles[a])
+						}
 					}
 				}
 			}
 		}
 	}
+}
+
+// walkParts calls part and book for the parts and books of a code in document
+// order: each part comes right before its first book. Parts without books are
+// visited where they stand in code.Parts.
+func walkParts(code *ParsedCode, part func(*Part), book func(*Book)) {
+	next := 0
+	seen := map[string]bool{}
+	// visits the parts up to the one with the given ID, or all of them
+	partsUpTo := func(id string) {
+		for next < len(code.Parts) && (id == "" || !seen[id]) {
+			p := &code.Parts[next]
+			next++
+			seen[p.ID] = true
+			part(p)
+		}
+	}
+	for i := range code.Books {
+		if id := code.Books[i].Part; id != "" {
+			partsUpTo(id)
+		}
+		book(&code.Books[i])
+	}
+	partsUpTo("")
+}
+
+// collectArticles gathers all articles of the hierarchy into code.Articles
+// and updates the article count.
+func collectArticles(code *ParsedCode) {
+	var all []Article
+	walkArticles(code, func(a *Article) {
+		all = append(all, *a)
+	})
 	code.TotalArticles = len(all)
 	code.Articles = all
-	return code, nil
+}
+
+// findArticle returns the article with the given number, if any.
+func findArticle(code *ParsedCode, number string) *Article {
+	number = normalizeArticleNumber(number)
+	for i := range code.Articles {
+		if normalizeArticleNumber(code.Articles[i].Number) == number {
+			return &code.Articles[i]
+		}
+	}
+	return nil
+}
+
+// articleNumberPattern matches the article numbers found in the codes:
+// "86", "2.663" (thousands separator), "86^1" and "281 bis".
+const articleNumberPattern = `\d+(?:\.\d{3})*(?:\s*\^\s*\d+|[¹²³⁴⁵⁶⁷⁸⁹⁰]+|\s+(?:bis|ter|quater|quinquies|sexies|septies|octies|nonies|decies)\b)?`
+
+var articleNumberRe = regexp.MustCompile(`(?i)^(\d+(?:\.\d{3})*)\s*(?:\^\s*(\d+)|([¹²³⁴⁵⁶⁷⁸⁹⁰]+)|(bis|ter|quater|quinquies|sexies|septies|octies|nonies|decies))?\.?$`)
+
+// latin ordinals used for inserted articles, "281 bis" being 281^1
+var latinOrdinals = map[string]int{
+	"bis": 1, "ter": 2, "quater": 3, "quinquies": 4, "sexies": 5,
+	"septies": 6, "octies": 7, "nonies": 8, "decies": 9,
+}
+
+var superscriptDigits = strings.NewReplacer("¹", "1", "²", "2", "³", "3", "⁴", "4", "⁵", "5", "⁶", "6", "⁷", "7", "⁸", "8", "⁹", "9", "⁰", "0")
+
+// normalizeArticleNumber brings an article number to the canonical form used
+// for IDs and lookups: thousands separators are dropped and inserted articles
+// are written with a caret ("2.663" -> "2663", "86¹" and "86 bis" -> "86^1").
+func normalizeArticleNumber(n string) string {
+	n = strings.TrimSpace(n)
+	m := articleNumberRe.FindStringSubmatch(n)
+	if m == nil {
+		return strings.ReplaceAll(n, ".", "")
+	}
+	base := strings.ReplaceAll(m[1], ".", "")
+	switch {
+	case m[2] != "":
+		return base + "^" + m[2]
+	case m[3] != "":
+		return base + "^" + superscriptDigits.Replace(m[3])
+	case m[4] != "":
+		return fmt.Sprintf("%s^%d", base, latinOrdinals[strings.ToLower(m[4])])
+	}
+	return base
+}
+
+// articleSortKey turns an article number into an integer that sorts inserted
+// articles right after their base article (86 < 86^1 < 86^2 < 87).
+func articleSortKey(number string) int {
+	n := normalizeArticleNumber(number)
+	base, index := n, 0
+	if i := strings.Index(n, "^"); i >= 0 {
+		base = n[:i]
+		index, _ = strconv.Atoi(n[i+1:])
+	}
+	b, _ := strconv.Atoi(base)
+	return b*1000 + index
+}
+
+// appendParagraphLine files a content line into the article's paragraph tree:
+// "(n)" opens a new paragraph and "a)" adds a letter to the current paragraph.
+// Any other line continues the last letter when it does not end like a list
+// item ("prin:", a wrapped line), the closing text after the letters, or the
+// current paragraph when it has no letters.
+func appendParagraphLine(a *Article, line string) {
+	if m := paragraphRe.FindStringSubmatch(line); m != nil {
+		a.Paragraphs = append(a.Paragraphs, Paragraph{Number: m[1], Text: m[2]})
+		return
+	}
+	if len(a.Paragraphs) == 0 {
+		a.Paragraphs = append(a.Paragraphs, Paragraph{})
+	}
+	p := &a.Paragraphs[len(a.Paragraphs)-1]
+	if m := letterRe.FindStringSubmatch(line); m != nil {
+		p.Letters = append(p.Letters, Letter{Letter: m[1], Text: m[2]})
+		return
+	}
+	text := &p.Text
+	if n := len(p.Letters); n > 0 {
+		text = &p.Closing
+		if l := &p.Letters[n-1]; !letterEndRe.MatchString(l.Text) {
+			text = &l.Text
+		}
+	}
+	if *text != "" {
+		*text += "\n" + line
+	} else {
+		*text = line
+	}
+}
+
+// attachNote stores a note on the article and on the paragraph it follows.
+func attachNote(a *Article, note string) {
+	a.Notes = append(a.Notes, note)
+	if len(a.Paragraphs) > 0 {
+		p := &a.Paragraphs[len(a.Paragraphs)-1]
+		p.Notes = append(p.Notes, note)
+	}
 }
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAppendParagraphLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  []Paragraph
+	}{
+		{
+			name:  "text without numbered paragraphs",
+			lines: []string{"Legea penală se aplică infracțiunilor săvârșite în timpul cât ea se află în vigoare."},
+			want:  []Paragraph{{Text: "Legea penală se aplică infracțiunilor săvârșite în timpul cât ea se află în vigoare."}},
+		},
+		{
+			name: "numbered paragraphs",
+			lines: []string{
+				"(1) Lipsirea de libertate a unei persoane în mod ilegal se pedepsește cu închisoarea de la unu la 7 ani.",
+				"(2) Se consideră lipsire de libertate și răpirea unei persoane aflate în imposibilitatea de a-și exprima voința ori de a se apăra.",
+			},
+			want: []Paragraph{
+				{Number: "1", Text: "Lipsirea de libertate a unei persoane în mod ilegal se pedepsește cu închisoarea de la unu la 7 ani."},
+				{Number: "2", Text: "Se consideră lipsire de libertate și răpirea unei persoane aflate în imposibilitatea de a-și exprima voința ori de a se apăra."},
+			},
+		},
+		{
+			name: "letters followed by closing text",
+			lines: []string{
+				"(3) Dacă fapta este săvârșită:",
+				"a) de către o persoană înarmată;",
+				"b) asupra unui minor;",
+				"c) punând în pericol sănătatea sau viața victimei,",
+				"pedeapsa este închisoarea cuprinsă între 3 și 10 ani.",
+				"(4) Dacă fapta a avut ca urmare moartea victimei, pedeapsa este închisoarea de la 7 la 15 ani și interzicerea exercitării unor drepturi.",
+			},
+			want: []Paragraph{
+				{
+					Number: "3",
+					Text:   "Dacă fapta este săvârșită:",
+					Letters: []Letter{
+						{Letter: "a", Text: "de către o persoană înarmată;"},
+						{Letter: "b", Text: "asupra unui minor;"},
+						{Letter: "c", Text: "punând în pericol sănătatea sau viața victimei,"},
+					},
+					Closing: "pedeapsa este închisoarea cuprinsă între 3 și 10 ani.",
+				},
+				{Number: "4", Text: "Dacă fapta a avut ca urmare moartea victimei, pedeapsa este închisoarea de la 7 la 15 ani și interzicerea exercitării unor drepturi."},
+			},
+		},
+		{
+			name: "points continuing a letter",
+			lines: []string{
+				"g) expunerea unei persoane la un pericol de moarte sau atingere gravă adusă sănătății prin:",
+				"1. efectuarea asupra acesteia de experiențe cu privire la care ea nu a consimțit în mod voluntar, expres și prealabil sau care nu sunt necesare pentru sănătatea acesteia ori nu sunt efectuate în interesul său;",
+				"h) supunerea unei persoane la un tratament degradant, se pedepsește cu detențiune pe viață sau cu închisoare de la 15 la 25 de ani și interzicerea exercitării unor drepturi.",
+			},
+			want: []Paragraph{{Letters: []Letter{
+				{Letter: "g", Text: "expunerea unei persoane la un pericol de moarte sau atingere gravă adusă sănătății prin:\n1. efectuarea asupra acesteia de experiențe cu privire la care ea nu a consimțit în mod voluntar, expres și prealabil sau care nu sunt necesare pentru sănătatea acesteia ori nu sunt efectuate în interesul său;"},
+				{Letter: "h", Text: "supunerea unei persoane la un tratament degradant, se pedepsește cu detențiune pe viață sau cu închisoare de la 15 la 25 de ani și interzicerea exercitării unor drepturi."},
+			}}},
+		},
+		{
+			name: "letters with diacritics",
+			lines: []string{
+				"s) art. 21-33 din Legea locuinței nr. 114/1996, republicată în Monitorul Oficial al României, Partea I, nr. 393 din 31 decembrie 1997;",
+				"ș) art. 7, 14 și 15 din Legea nr. 119/1996 cu privire la actele de stare civilă, republicată în Monitorul Oficial al României, Partea I, nr. 743 din 2 noiembrie 2009, cu modificările ulterioare",
+				"t) art. 32 din Legea fondului funciar nr. 18/1991, republicată în Monitorul Oficial al României, Partea I, nr. 1 din 5 ianuarie 1998, cu modificările și completările ulterioare;",
+			},
+			want: []Paragraph{{Letters: []Letter{
+				{Letter: "s", Text: "art. 21-33 din Legea locuinței nr. 114/1996, republicată în Monitorul Oficial al României, Partea I, nr. 393 din 31 decembrie 1997;"},
+				{Letter: "ș", Text: "art. 7, 14 și 15 din Legea nr. 119/1996 cu privire la actele de stare civilă, republicată în Monitorul Oficial al României, Partea I, nr. 743 din 2 noiembrie 2009, cu modificările ulterioare"},
+				{Letter: "t", Text: "art. 32 din Legea fondului funciar nr. 18/1991, republicată în Monitorul Oficial al României, Partea I, nr. 1 din 5 ianuarie 1998, cu modificările și completările ulterioare;"},
+			}}},
+		},
+		{
+			name: "inserted paragraph",
+			lines: []string{
+				"(1^1) Are capacitate de exercițiu restrânsă și majorul care beneficiază de consiliere judiciară.",
+			},
+			want: []Paragraph{{Number: "1^1", Text: "Are capacitate de exercițiu restrânsă și majorul care beneficiază de consiliere judiciară."}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var a Article
+			for _, line := range tt.lines {
+				appendParagraphLine(&a, line)
+			}
+			if !reflect.DeepEqual(a.Paragraphs, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", a.Paragraphs, tt.want)
+			}
+		})
+	}
+}
+
+// Codul penal, art. 5: each note follows the paragraph it comments on.
+const notesBetweenParagraphs = `Articolul 5
+
+Aplicarea legii penale mai favorabile până la judecarea definitivă a cauzei
+(1) În cazul în care de la săvârșirea infracțiunii până la judecarea definitivă a cauzei au intervenit una sau mai multe legi penale, se aplică legea mai favorabilă.
+Notă
+Decizie de admitere: HP nr. 21/2014, publicată în Monitorul Oficial nr. 829 din 13 noiembrie 2014:
+
+Stabilește că dispozițiile art. 5 alin. 1 din Codul penal trebuie interpretate, inclusiv în materia prescripției răspunderii penale, în sensul că legea penală mai favorabilă este aplicabilă în cazul infracțiunilor săvârșite anterior datei de 1 februarie 2014 care nu au fost încă judecate definitiv, în conformitate cu Decizia nr. 265/2014 a Curții Constituționale.
+(2) Dispozițiile alin. (1) se aplică și actelor normative ori prevederilor din acestea declarate neconstituționale, precum și ordonanțelor de urgență aprobate de Parlament cu modificări sau completări ori respinse, dacă în timpul când acestea s-au aflat în vigoare au cuprins dispoziții penale mai favorabile.
+Notă
+Prin DECIZIA CURȚII CONSTITUȚIONALE nr. 265 din 6 mai 2014, publicată în MONITORUL OFICIAL nr. 372 din 20 mai 2014, s-a admis excepția de neconstituționalitate referitoare la dispozițiile art. 5 din Codul penal.
+`
+
+func TestParseParagraphNotes(t *testing.T) {
+	g, err := loadGrammar("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc := parseCodeLines(strings.Split(notesBetweenParagraphs, "\n"), "penal", "Codul Penal", g)
+	if len(pc.Articles) != 1 {
+		t.Fatalf("parsed %d articles, want 1", len(pc.Articles))
+	}
+	a := pc.Articles[0]
+	if a.Title != "Aplicarea legii penale mai favorabile până la judecarea definitivă a cauzei" {
+		t.Errorf("title %q", a.Title)
+	}
+	if len(a.Paragraphs) != 2 {
+		t.Fatalf("parsed %d paragraphs, want 2", len(a.Paragraphs))
+	}
+	for i, prefix := range []string{"Decizie de admitere: HP nr. 21/2014", "Prin DECIZIA CURȚII CONSTITUȚIONALE nr. 265"} {
+		p := a.Paragraphs[i]
+		if p.Number != []string{"1", "2"}[i] {
+			t.Errorf("paragraph %d numbered %q", i, p.Number)
+		}
+		if len(p.Notes) != 1 || !strings.HasPrefix(p.Notes[0], "Notă\n"+prefix) {
+			t.Errorf("paragraph %s notes %q, want one starting with %q", p.Number, p.Notes, prefix)
+		}
+	}
+	if len(a.Notes) != 2 {
+		t.Errorf("article has %d notes, want 2", len(a.Notes))
+	}
+}
+
+func TestNormalizeArticleNumber(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"86", "86"},
+		{"2.663", "2663"},
+		{"2.029^1", "2029^1"},
+		{"86^1", "86^1"},
+		{"86 ^ 2", "86^2"},
+		{"86¹", "86^1"},
+		{"281 bis", "281^1"},
+		{"281 ter", "281^2"},
+		{"211.", "211"},
+		{" 1.000 ", "1000"},
+	}
+	for _, tt := range tests {
+		if got := normalizeArticleNumber(tt.in); got != tt.want {
+			t.Errorf("normalizeArticleNumber(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestArticleSortKey(t *testing.T) {
+	ordered := []string{"85", "86", "86^1", "86^2", "87", "999", "1.000", "2.029", "2.029^1", "2.029^3", "2.030"}
+	for i := 1; i < len(ordered); i++ {
+		a, b := articleSortKey(ordered[i-1]), articleSortKey(ordered[i])
+		if a >= b {
+			t.Errorf("articleSortKey(%q) = %d, not before articleSortKey(%q) = %d", ordered[i-1], a, ordered[i], b)
+		}
+	}
+	if got := articleSortKey("86 bis"); got != articleSortKey("86^1") {
+		t.Errorf("86 bis sorts at %d, 86^1 at %d", got, articleSortKey("86^1"))
+	}
+}
+
+// Codul civil, art. 2.029 to 2.030: inserted articles are numbered with a
+// caret and the thousands separator is dropped from numbers and IDs.
+func TestParseInsertedArticles(t *testing.T) {
+	g, err := loadGrammar("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := []string{
+		"Articolul 2.029",
+		"Articolul 2.029^1",
+		"Articolul 2.029^2",
+		"Articolul 2.030",
+	}
+	pc := parseCodeLines(lines, "civil", "Codul Civil", g)
+	want := []struct {
+		number, id string
+		sortKey    int
+	}{
+		{"2029", "civil/art-2029", 2029000},
+		{"2029^1", "civil/art-2029^1", 2029001},
+		{"2029^2", "civil/art-2029^2", 2029002},
+		{"2030", "civil/art-2030", 2030000},
+	}
+	if len(pc.Articles) != len(want) {
+		t.Fatalf("parsed %d articles, want %d", len(pc.Articles), len(want))
+	}
+	for i, w := range want {
+		a := pc.Articles[i]
+		if a.Number != w.number || a.ID != w.id || a.SortKey != w.sortKey {
+			t.Errorf("article %d: got %s %s %d, want %s %s %d", i, a.Number, a.ID, a.SortKey, w.number, w.id, w.sortKey)
+		}
+	}
+	if a := findArticle(pc, "2.029^1"); a == nil || a.ID != "civil/art-2029^1" {
+		t.Errorf("findArticle(2.029^1) = %+v", a)
+	}
+}
+
+// Codul de procedură civilă, Cartea a V-a: heading names wrapped over several
+// lines are joined, whether they start on the heading line or on the next.
+func TestParseWrappedHeadings(t *testing.T) {
+	g, err := loadGrammar("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := []string{
+		"Cartea a V-a",
+		"Despre executarea",
+		"silită",
+		"Titlul II Efectuarea executării silite și",
+		"urmărirea bunurilor debitorului",
+		"Capitolul IV Executarea silită a altor obligații de a face sau a obligațiilor",
+		"de a nu face",
+		"Secţiunea 1 Dispoziții comune",
+		"Articolul 903",
+		"",
+		"Sesizarea instanței de executare",
+		"(1) Dacă debitorul nu execută de bunăvoie obligația de a face sau de a nu face, creditorul poate cere instanței de executare să îl autorizeze să o îndeplinească el însuși.",
+	}
+	pc := parseCodeLines(lines, "proc_civil", "Codul de Procedură Civilă", g)
+	if len(pc.Books) != 1 || len(pc.Books[0].Titles) != 1 || len(pc.Books[0].Titles[0].Chapters) != 1 {
+		t.Fatalf("parsed %+v, want one book, title and chapter", pc.Books)
+	}
+	b := pc.Books[0]
+	title := b.Titles[0]
+	ch := title.Chapters[0]
+	got := []string{b.Title, b.Subtitle, title.Title, title.Subtitle, ch.Title, ch.Subtitle}
+	want := []string{
+		"Cartea a V-a", "Despre executarea silită",
+		"Titlul II", "Efectuarea executării silite și urmărirea bunurilor debitorului",
+		"Capitolul IV", "Executarea silită a altor obligații de a face sau a obligațiilor de a nu face",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("headings %q, want %q", got, want)
+	}
+	if len(pc.Articles) != 1 || pc.Articles[0].Title != "Sesizarea instanței de executare" || len(pc.Articles[0].Paragraphs) != 1 {
+		t.Errorf("articles %+v", pc.Articles)
+	}
+}
+
+// Codul penal has no books: its titles come right after "Partea GENERALĂ" and
+// "Partea SPECIALĂ" and are kept in an unnamed book of each part, without a
+// missing-heading warning.
+func TestParseTitlesUnderPart(t *testing.T) {
+	g, err := loadGrammar("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := []string{
+		"Partea GENERALĂ",
+		"Titlul I Legea penală și limitele ei de aplicare",
+		"Capitolul I Principii generale",
+		"Articolul 1",
+		"Legalitatea incriminării",
+		"(1) Legea penală prevede faptele care constituie infracțiuni.",
+		"Titlul II Infracțiunea",
+		"Capitolul I Dispoziții generale",
+		"Articolul 15",
+		"Trăsăturile esențiale ale infracțiunii",
+		"(1) Infracțiunea este fapta prevăzută de legea penală, săvârșită cu vinovăție, nejustificată și imputabilă persoanei care a săvârșit-o.",
+		"Partea SPECIALĂ",
+		"Titlul I Infracțiuni contra persoanei",
+		"Capitolul I Infracțiuni contra vieții",
+		"Articolul 188",
+		"Omorul",
+		"(1) Uciderea unei persoane se pedepsește cu închisoare de la 10 la 20 de ani și interzicerea exercitării unor drepturi.",
+	}
+	pc := parseCodeLines(lines, "penal", "Codul Penal", g)
+	for _, d := range pc.Diagnostics {
+		if strings.Contains(d.Message, "placeholder") {
+			t.Errorf("diagnostic on line %d: %s", d.Line, d.Message)
+		}
+	}
+	if len(pc.Parts) != 2 || len(pc.Books) != 2 {
+		t.Fatalf("%d parts and %d books, want 2 of each", len(pc.Parts), len(pc.Books))
+	}
+	for i, want := range []int{2, 1} {
+		b := pc.Books[i]
+		if b.Title != "" || b.Part != pc.Parts[i].ID || len(b.Titles) != want {
+			t.Errorf("book %d: title %q, part %q, %d titles, want an unnamed book of %s with %d", i, b.Title, b.Part, len(b.Titles), pc.Parts[i].ID, want)
+		}
+	}
+	// headings opening a book are numbered within it
+	if b := pc.Books[1]; b.ID != "book_2" || b.Titles[0].ID != "book_2_title_1" || b.Titles[0].Chapters[0].ID != "book_2_title_1_ch_1" {
+		t.Errorf("ids %s, %s and %s, want them all in book_2", b.ID, b.Titles[0].ID, b.Titles[0].Chapters[0].ID)
+	}
+
+	nodes, _ := codeNavigation(pc)
+	outline := outlineTree(nodes, -1, 2, false)
+	var got []string
+	for _, part := range outline {
+		for _, child := range part.Children {
+			got = append(got, part.Title+" > "+child.Kind+" "+child.Title)
+		}
+	}
+	want := []string{"Partea GENERALĂ > title Titlul I", "Partea GENERALĂ > title Titlul II", "Partea SPECIALĂ > title Titlul I"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("outline %q, want %q", got, want)
+	}
+}
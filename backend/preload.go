@@ -0,0 +1,288 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CodeStatus reports how far the startup parsing of a code got. State is
+// "pending", "parsing", "ready" or "failed"; Source tells whether the cached
+// JSON was still valid ("cache") or the text had to be parsed ("parsed").
+type CodeStatus struct {
+	ID         string `json:"id"`
+	Title      string `json:"title"`
+	State      string `json:"state"`
+	Source     string `json:"source,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Articles   int    `json:"articles,omitempty"`
+	StartedAt  string `json:"startedAt,omitempty"`
+	FinishedAt string `json:"finishedAt,omitempty"`
+	DurationMS int64  `json:"durationMs,omitempty"`
+}
+
+// sourceFingerprint identifies the text a cached code_<id>.json was parsed
+// from and the parser version that parsed it. The hash is only computed when
+// the modification time changed, so touching a file without editing it does
+// not cause a new parse.
+type sourceFingerprint struct {
+	Path          string `json:"path"`
+	ModTime       string `json:"modTime"`
+	Size          int64  `json:"size"`
+	SHA256        string `json:"sha256"`
+	ParserVersion int    `json:"parserVersion"`
+}
+
+// parsing is mostly CPU bound and every parse holds a whole code in memory
+const maxPreloadWorkers = 4
+
+var parsedSourcesFile = filepath.Join(dataDir, "parsed_sources.json")
+
+var (
+	preloadMu     sync.Mutex
+	codeStatuses  = map[string]*CodeStatus{}
+	preloadDone   = map[string]chan struct{}{}
+	parsedSources = map[string]sourceFingerprint{}
+)
+
+// preloadParsedCodes makes sure every known code has an up to date
+// code_<id>.json next to the React dashboard, so that requests do not have
+// to parse the texts. A JSON file is regenerated when the modification time
+// and hash of its source text no longer match the ones it was parsed from,
+// or when it was written by another version of the parser.
+//
+// It returns at once: codes are parsed in the background by a bounded pool
+// of workers while the server is already listening, and their progress is
+// reported by /api/codes/status. A code that fails to parse, or makes the
+// parser panic, is marked failed without stopping the others.
+func preloadParsedCodes() {
+	if data, err := os.ReadFile(parsedSourcesFile); err == nil {
+		json.Unmarshal(data, &parsedSources)
+	}
+	ids := make([]string, 0, len(codeFiles))
+	preloadMu.Lock()
+	for id, info := range codeFiles {
+		ids = append(ids, id)
+		codeStatuses[id] = &CodeStatus{ID: id, Title: info.title, State: "pending"}
+		preloadDone[id] = make(chan struct{})
+	}
+	preloadMu.Unlock()
+	sort.Strings(ids)
+
+	workers := runtime.NumCPU()
+	if workers > maxPreloadWorkers {
+		workers = maxPreloadWorkers
+	}
+	jobs := make(chan string, len(ids))
+	for _, id := range ids {
+		jobs <- id
+	}
+	close(jobs)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range jobs {
+				preloadCode(id)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		if err := saveParsedSources(); err != nil {
+			fmt.Println("failed to save", parsedSourcesFile, "-", err)
+		}
+	}()
+}
+
+// preloadCode brings the code_<id>.json of one code up to date and records
+// the outcome in its status.
+func preloadCode(id string) {
+	info := codeFiles[id]
+	started := time.Now()
+	setCodeStatus(id, func(s *CodeStatus) {
+		s.State = "parsing"
+		s.StartedAt = started.Format(time.RFC3339)
+	})
+	var (
+		source   string
+		articles int
+		err      error
+	)
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("parser panic: %v", r)
+		}
+		setCodeStatus(id, func(s *CodeStatus) {
+			s.FinishedAt = time.Now().Format(time.RFC3339)
+			s.DurationMS = time.Since(started).Milliseconds()
+			if err != nil {
+				fmt.Println("failed to parse", id, "-", err)
+				s.State, s.Error = "failed", err.Error()
+				return
+			}
+			s.State, s.Source, s.Articles = "ready", source, articles
+		})
+		preloadMu.Lock()
+		close(preloadDone[id])
+		preloadMu.Unlock()
+	}()
+
+	jsonPath := filepath.Join(rootDir, "dashbord-react", fmt.Sprintf("code_%s.json", id))
+	fp, fresh, err := checkSource(id, info.path, jsonPath)
+	if err != nil {
+		return
+	}
+	if fresh {
+		var pc struct {
+			TotalArticles int `json:"totalArticles"`
+			ParserVersion int `json:"parserVersion"`
+		}
+		if data, rerr := os.ReadFile(jsonPath); rerr == nil && json.Unmarshal(data, &pc) == nil && pc.ParserVersion == parserVersion {
+			source, articles = "cache", pc.TotalArticles
+			return
+		}
+	}
+
+	pc, err := buildParsedCode(info.path, id, info.title)
+	if err != nil {
+		return
+	}
+	storeDiagnostics(id, fp, pc.Diagnostics)
+	if pc.TableOfContents != nil {
+		for _, m := range pc.TableOfContents.Mismatches {
+			fmt.Println("table of contents mismatch in", id, "-", m)
+		}
+	}
+	pc.LastUpdated = time.Now().Format(time.RFC3339)
+	data, err := json.MarshalIndent(pc, "", "  ")
+	if err != nil {
+		return
+	}
+	if err = os.WriteFile(jsonPath, data, 0644); err != nil {
+		return
+	}
+	cacheRemove(id)
+	reindexCode(id, nil)
+	invalidateSuggestions(id)
+	preloadMu.Lock()
+	parsedSources[id] = fp
+	preloadMu.Unlock()
+	source, articles = "parsed", pc.TotalArticles
+}
+
+// checkSource fingerprints the source text of a code and reports whether
+// the JSON at jsonPath was parsed from that same text by this version of the
+// parser. A JSON file without a recorded fingerprint is never trusted.
+func checkSource(id, path, jsonPath string) (sourceFingerprint, bool, error) {
+	fp, err := statSource(path)
+	if err != nil {
+		return fp, false, err
+	}
+	preloadMu.Lock()
+	old, known := parsedSources[id]
+	preloadMu.Unlock()
+	if _, err := os.Stat(jsonPath); err != nil || !known || old.ParserVersion != parserVersion {
+		fp.SHA256, err = fileSHA256(path)
+		return fp, false, err
+	}
+	if old.sameFile(fp) {
+		fp.SHA256 = old.SHA256
+		return fp, true, nil
+	}
+	if fp.SHA256, err = fileSHA256(path); err != nil {
+		return fp, false, err
+	}
+	fresh := old.SHA256 == fp.SHA256
+	if fresh {
+		preloadMu.Lock()
+		parsedSources[id] = fp
+		preloadMu.Unlock()
+	}
+	return fp, fresh, nil
+}
+
+// statSource fingerprints a source text without hashing it.
+func statSource(path string) (sourceFingerprint, error) {
+	st, err := os.Stat(path)
+	if err != nil {
+		return sourceFingerprint{}, err
+	}
+	return sourceFingerprint{Path: path, ModTime: st.ModTime().UTC().Format(time.RFC3339Nano), Size: st.Size(), ParserVersion: parserVersion}, nil
+}
+
+// sameFile reports whether two fingerprints were taken of the same file,
+// unmodified since, by the same parser.
+func (fp sourceFingerprint) sameFile(o sourceFingerprint) bool {
+	return fp.Path == o.Path && fp.ModTime == o.ModTime && fp.Size == o.Size && fp.ParserVersion == o.ParserVersion
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func saveParsedSources() error {
+	preloadMu.Lock()
+	data, err := json.MarshalIndent(parsedSources, "", "  ")
+	preloadMu.Unlock()
+	if err != nil {
+		return err
+	}
+	os.MkdirAll(dataDir, 0755)
+	return os.WriteFile(parsedSourcesFile, data, 0644)
+}
+
+func setCodeStatus(id string, update func(*CodeStatus)) {
+	preloadMu.Lock()
+	defer preloadMu.Unlock()
+	if s, ok := codeStatuses[id]; ok {
+		update(s)
+	}
+}
+
+// waitForPreload blocks while the startup parsing of a code is running, so
+// that requests arriving meanwhile do not parse the same text again.
+func waitForPreload(id string) {
+	preloadMu.Lock()
+	done, ok := preloadDone[id]
+	preloadMu.Unlock()
+	if ok {
+		<-done
+	}
+}
+
+// codesStatusHandler returns the startup parsing status of every code.
+func codesStatusHandler(c *gin.Context) {
+	preloadMu.Lock()
+	out := make([]CodeStatus, 0, len(codeStatuses))
+	ready := 0
+	for _, s := range codeStatuses {
+		out = append(out, *s)
+		if s.State == "ready" {
+			ready++
+		}
+	}
+	preloadMu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	c.JSON(http.StatusOK, gin.H{"ready": ready, "total": len(out), "codes": out})
+}
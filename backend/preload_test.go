@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testCodeText = `Articolul 1
+
+Legalitatea incriminării
+(1) Legea penală prevede faptele care constituie infracțiuni.
+(2) Nicio persoană nu poate fi sancționată penal pentru o faptă care nu era prevăzută de legea penală la data când a fost săvârșită.
+
+Articolul 2
+
+Legalitatea sancțiunilor de drept penal
+(1) Legea penală prevede pedepsele aplicabile și măsurile educative ce se pot lua față de persoanele care au săvârșit infracțiuni, precum și măsurile de siguranță ce se pot lua față de persoanele care au comis fapte prevăzute de legea penală.
+`
+
+// useTestCode registers the code "test" with the given text and keeps its
+// stored JSON, snapshots, versions and text in a temporary directory for the
+// duration of the test. It returns the path of the stored JSON.
+func useTestCode(t *testing.T, text string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "dashbord-react"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "test.txt")
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	oldRoot, oldSnapshots, oldVersions := rootDir, snapshotsDir, versionsDir
+	rootDir, snapshotsDir, versionsDir = dir, filepath.Join(dir, "code_snapshots"), filepath.Join(dir, "versiuni")
+	codeFiles["test"] = codeFile{path: path, title: "Codul de test"}
+	cacheRemove("test")
+	t.Cleanup(func() {
+		rootDir, snapshotsDir, versionsDir = oldRoot, oldSnapshots, oldVersions
+		delete(codeFiles, "test")
+		cacheRemove("test")
+		versionsMu.Lock()
+		delete(storedVersions, "test")
+		versionsMu.Unlock()
+	})
+	return filepath.Join(dir, "dashbord-react", "code_test.json")
+}
+
+// A code_<id>.json written by an older parser, without paragraphs or a
+// parser version, is parsed again before it is served.
+func TestParsedCodeReplacesOldVersion(t *testing.T) {
+	jsonPath := useTestCode(t, testCodeText)
+	old := `{"id":"test","title":"Codul de test","books":[],"totalArticles":2,"articles":[{"id":"art_1","number":"1","content":"(1) Legea penală prevede faptele care constituie infracțiuni."}]}`
+	if err := os.WriteFile(jsonPath, []byte(old), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/parsed-code/:id", getParsedCodeHandler)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/parsed-code/test", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var pc ParsedCode
+	if err := json.Unmarshal(w.Body.Bytes(), &pc); err != nil {
+		t.Fatal(err)
+	}
+	if pc.ParserVersion != parserVersion || len(pc.Articles) != 2 || len(pc.Articles[0].Paragraphs) != 2 || pc.Articles[0].ID != "test/art-1" {
+		t.Errorf("served the old file: version %d, %d articles", pc.ParserVersion, len(pc.Articles))
+	}
+	data, err := os.ReadFile(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored ParsedCode
+	if err := json.Unmarshal(data, &stored); err != nil || stored.ParserVersion != parserVersion {
+		t.Errorf("stored file not replaced: version %d, %v", stored.ParserVersion, err)
+	}
+	if v, err := currentParsedVersion("test"); err != nil || w.Header().Get("ETag") != v.etag {
+		t.Errorf("ETag %s, want the one of the stored file %+v (%v)", w.Header().Get("ETag"), v, err)
+	}
+}
+
+// A JSON file without a recorded fingerprint is parsed again, even when it
+// is newer than its text.
+func TestCheckSourceWithoutFingerprint(t *testing.T) {
+	jsonPath := useTestCode(t, testCodeText)
+	if err := os.WriteFile(jsonPath, []byte(`{"id":"test"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	preloadMu.Lock()
+	delete(parsedSources, "test")
+	preloadMu.Unlock()
+	path := codeFiles["test"].path
+	fp, fresh, err := checkSource("test", path, jsonPath)
+	if err != nil || fresh {
+		t.Fatalf("checkSource without fingerprint: fresh %v, %v", fresh, err)
+	}
+
+	preloadMu.Lock()
+	parsedSources["test"] = fp
+	preloadMu.Unlock()
+	defer func() {
+		preloadMu.Lock()
+		delete(parsedSources, "test")
+		preloadMu.Unlock()
+	}()
+	if _, fresh, _ := checkSource("test", path, jsonPath); !fresh {
+		t.Error("checkSource with the same fingerprint: not fresh")
+	}
+	fp.ParserVersion = parserVersion - 1
+	preloadMu.Lock()
+	parsedSources["test"] = fp
+	preloadMu.Unlock()
+	if _, fresh, _ := checkSource("test", path, jsonPath); fresh {
+		t.Error("checkSource with another parser version: fresh")
+	}
+}
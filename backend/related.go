@@ -0,0 +1,255 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"math"
+	"net/http"
+	"sort"
+)
+
+// RelatedArticle is an article of the same code recommended next to another
+// one. Similarity is the cosine of the TF-IDF vectors of their titles and
+// texts; Score adds the citations and court decisions they share and a bonus
+// when one of them cites the other.
+type RelatedArticle struct {
+	ID              string  `json:"id"`
+	Number          string  `json:"number"`
+	Title           string  `json:"title"`
+	Score           float64 `json:"score"`
+	Similarity      float64 `json:"similarity"`
+	SharedCitations int     `json:"sharedCitations,omitempty"`
+	SharedDecisions int     `json:"sharedDecisions,omitempty"`
+	Cites           bool    `json:"cites,omitempty"`
+}
+
+// neighbours kept per article and the score below which an article is not
+// worth recommending
+const (
+	maxRelated      = 5
+	minRelatedScore = 0.15
+)
+
+// weight of the links between two articles in their score; shared citations
+// and decisions only count up to a few
+const (
+	sharedCitationWeight = 0.1
+	maxSharedCitations   = 3
+	sharedDecisionWeight = 0.15
+	maxSharedDecisions   = 2
+	directCitationWeight = 0.2
+)
+
+// relateArticles fills the related articles of every article of a code.
+// Repealed articles get none and are never recommended.
+//
+// Terms are the stems used by the search, so that "contractul" and
+// "contractului" count as the same word; title terms count twice. Only the
+// citations of the article text are compared, the ones of the notes being
+// about amending acts.
+func relateArticles(code *ParsedCode) {
+	n := len(code.Articles)
+	vectors := make([]map[string]float64, n)
+	df := map[string]int{}
+	for i, a := range code.Articles {
+		if a.Repealed {
+			continue
+		}
+		tf := map[string]float64{}
+		for _, t := range searchTokens(a.Title) {
+			tf[t.term] += 2
+		}
+		for _, t := range searchTokens(a.Content) {
+			tf[t.term]++
+		}
+		for t := range tf {
+			df[t]++
+		}
+		vectors[i] = tf
+	}
+
+	type posting struct {
+		doc int
+		w   float64
+	}
+	postings := map[string][]posting{}
+	for i, v := range vectors {
+		norm := 0.0
+		for t, f := range v {
+			w := (1 + math.Log(f)) * math.Log(float64(n)/float64(df[t]))
+			v[t] = w
+			norm += w * w
+		}
+		if norm == 0 {
+			continue
+		}
+		norm = math.Sqrt(norm)
+		for t := range v {
+			if v[t] /= norm; v[t] > 0 {
+				postings[t] = append(postings[t], posting{i, v[t]})
+			}
+		}
+	}
+
+	// the articles citing each article, and the ones citing each target or
+	// quoting each decision
+	ids := map[string]int{}
+	for i, a := range code.Articles {
+		if _, ok := ids[a.ID]; !ok {
+			ids[a.ID] = i
+		}
+	}
+	citing := map[string][]int{}
+	quoting := map[string][]int{}
+	for i, a := range code.Articles {
+		if vectors[i] == nil {
+			continue
+		}
+		self := articleKey(code.ID, a.Number)
+		seen := map[string]bool{}
+		for _, ct := range a.Citations {
+			key := articleKey(ct.Code, ct.Article)
+			if ct.Source != "content" || key == self || seen[key] {
+				continue
+			}
+			seen[key] = true
+			citing[key] = append(citing[key], i)
+		}
+		for _, d := range a.Decisions {
+			if !seen[d.ID] {
+				seen[d.ID] = true
+				quoting[d.ID] = append(quoting[d.ID], i)
+			}
+		}
+	}
+
+	// the links of article i to the others, kept in slices reused for every
+	// article since most articles share a word with most others
+	found := make([]*RelatedArticle, n)
+	var touched []int
+	get := func(j int) *RelatedArticle {
+		if found[j] == nil {
+			b := code.Articles[j]
+			found[j] = &RelatedArticle{ID: b.ID, Number: b.Number, Title: b.Title}
+			touched = append(touched, j)
+		}
+		return found[j]
+	}
+	similarity := make([]float64, n)
+	related := make([][]RelatedArticle, n)
+	for i, a := range code.Articles {
+		if vectors[i] == nil {
+			continue
+		}
+		self := articleKey(code.ID, a.Number)
+		seen := map[string]bool{}
+		for _, ct := range a.Citations {
+			key := articleKey(ct.Code, ct.Article)
+			if ct.Source != "content" || key == self || seen[key] {
+				continue
+			}
+			seen[key] = true
+			for _, j := range citing[key] {
+				if j != i {
+					get(j).SharedCitations++
+				}
+			}
+			if j, ok := ids[ct.TargetID]; ok && ct.TargetID != "" && j != i && vectors[j] != nil {
+				get(j).Cites = true
+			}
+		}
+		for _, j := range citing[self] {
+			if j != i {
+				get(j).Cites = true
+			}
+		}
+		for _, d := range a.Decisions {
+			if seen[d.ID] {
+				continue
+			}
+			seen[d.ID] = true
+			for _, j := range quoting[d.ID] {
+				if j != i {
+					get(j).SharedDecisions++
+				}
+			}
+		}
+
+		var similar []int
+		for t, w := range vectors[i] {
+			for _, p := range postings[t] {
+				if similarity[p.doc] == 0 {
+					similar = append(similar, p.doc)
+				}
+				similarity[p.doc] += w * p.w
+			}
+		}
+		for _, j := range similar {
+			// weak similarities are only kept for linked articles
+			if j != i && (found[j] != nil || similarity[j] >= minRelatedScore) {
+				get(j).Similarity = similarity[j]
+			}
+			similarity[j] = 0
+		}
+
+		var list []RelatedArticle
+		for _, j := range touched {
+			r := found[j]
+			found[j] = nil
+			r.Score = r.Similarity +
+				sharedCitationWeight*float64(minInt(r.SharedCitations, maxSharedCitations)) +
+				sharedDecisionWeight*float64(minInt(r.SharedDecisions, maxSharedDecisions))
+			if r.Cites {
+				r.Score += directCitationWeight
+			}
+			if r.Score >= minRelatedScore {
+				r.Score = math.Round(r.Score*1000) / 1000
+				r.Similarity = math.Round(r.Similarity*1000) / 1000
+				list = append(list, *r)
+			}
+		}
+		touched = touched[:0]
+		sort.Slice(list, func(x, y int) bool {
+			if list[x].Score != list[y].Score {
+				return list[x].Score > list[y].Score
+			}
+			return ids[list[x].ID] < ids[list[y].ID]
+		})
+		if len(list) > maxRelated {
+			list = list[:maxRelated]
+		}
+		related[i] = list
+	}
+
+	i := 0
+	walkArticles(code, func(a *Article) {
+		a.Related = related[i]
+		i++
+	})
+	collectArticles(code)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// getRelatedArticlesHandler serves the "see also" list of an article.
+func getRelatedArticlesHandler(c *gin.Context) {
+	pc, err := loadParsedCode(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "code not found"})
+		return
+	}
+	art := findArticle(pc, c.Param("number"))
+	if art == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "article not found"})
+		return
+	}
+	related := art.Related
+	if related == nil {
+		related = []RelatedArticle{}
+	}
+	c.JSON(http.StatusOK, gin.H{"id": art.ID, "number": art.Number, "related": related})
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const relatedCodeText = `Articolul 1
+Furtul
+(1) Luarea unui bun mobil din posesia altuia, fără consimțământul acestuia, se pedepsește cu închisoare.
+Articolul 2
+Furtul calificat
+(1) Furtul unui bun mobil săvârșit în timpul nopții, fără consimțământul posesorului, se pedepsește cu închisoare.
+Articolul 3
+Tâlhăria
+(1) Deposedarea prin violență se sancționează potrivit art. 5 și art. 8.
+Articolul 4
+Pirateria
+(1) Jefuirea unei nave pe marea liberă se sancționează potrivit art. 5 și art. 8.
+Articolul 5
+Limitele speciale
+(1) Limitele speciale se majorează cu o treime.
+Articolul 6
+Abrogat.
+Articolul 7
+Mărturia mincinoasă
+(1) Fapta martorului care face afirmații mincinoase se sancționează potrivit art. 6.
+Articolul 8
+Interzicerea drepturilor
+(1) Interzicerea exercitării unor drepturi constă în interdicția exercitării lor.
+`
+
+func TestRelateArticles(t *testing.T) {
+	g, err := loadGrammar("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc := parseCodeLines(strings.Split(relatedCodeText, "\n"), "penal", "Codul Penal", g)
+	analyzeParsedCode(pc)
+	related := map[string]string{}
+	for _, a := range pc.Articles {
+		s := ""
+		for _, r := range a.Related {
+			s += r.Number + " "
+		}
+		related[a.Number] = s
+	}
+	want := map[string]string{
+		// similar texts
+		"1": "2 ",
+		"2": "1 ",
+		// two shared citations, then the articles cited
+		"3": "4 5 8 ",
+		"4": "3 5 8 ",
+		// the articles citing them, in document order
+		"5": "3 4 ",
+		"8": "3 4 ",
+		// a repealed article has none and is never recommended
+		"6": "",
+		"7": "",
+	}
+	if !reflect.DeepEqual(related, want) {
+		t.Errorf("related %q, want %q", related, want)
+	}
+
+	art := func(number string) *Article { return findArticle(pc, number) }
+	if r := art("1").Related[0]; r.Similarity < minRelatedScore || r.Score != r.Similarity || r.SharedCitations != 0 || r.Cites {
+		t.Errorf("1 -> 2: %+v", r)
+	}
+	if r := art("3").Related[0]; r.SharedCitations != 2 || r.Cites || r.Score < 2*sharedCitationWeight {
+		t.Errorf("3 -> 4: %+v", r)
+	}
+	if r := art("3").Related[1]; !r.Cites || r.SharedCitations != 0 {
+		t.Errorf("3 -> 5: %+v", r)
+	}
+}
+
+func TestRelatedArticlesHandler(t *testing.T) {
+	useTestCode(t, relatedCodeText)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/codes/:id/articles/:number/related", getRelatedArticlesHandler)
+	tests := []struct {
+		path   string
+		status int
+		want   []string
+	}{
+		{"/codes/test/articles/5/related", http.StatusOK, []string{"test/art-3", "test/art-4"}},
+		{"/codes/test/articles/6/related", http.StatusOK, []string{}},
+		{"/codes/test/articles/99/related", http.StatusNotFound, nil},
+		{"/codes/nope/articles/1/related", http.StatusNotFound, nil},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.path, w.Code, tt.status)
+			continue
+		}
+		if tt.want == nil {
+			continue
+		}
+		var out struct {
+			Related []RelatedArticle `json:"related"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil || out.Related == nil {
+			t.Errorf("%s: %s", tt.path, w.Body)
+			continue
+		}
+		got := []string{}
+		for _, a := range out.Related {
+			got = append(got, a.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: related %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// codeAbbreviations maps the usual abbreviations of the codes, reduced to
+// their folded letters ("C.civ." -> "cciv", "NCPC" -> "ncpc"), to code ids.
+// The leading "n" marks the new codes, which are the only ones in codeFiles.
+var codeAbbreviations = map[string]string{
+	"cc": "civil", "cciv": "civil", "ccivil": "civil", "ncc": "civil", "ncciv": "civil", "codciv": "civil",
+	"cp": "penal", "cpen": "penal", "ncp": "penal", "ncpen": "penal", "codpen": "penal",
+	"cpc": "proc_civil", "ncpc": "proc_civil", "cprciv": "proc_civil", "cprocciv": "proc_civil", "ncprciv": "proc_civil", "ncprocciv": "proc_civil",
+	"cpp": "proc_penal", "ncpp": "proc_penal", "cprpen": "proc_penal", "cprocpen": "proc_penal", "ncprpen": "proc_penal", "ncprocpen": "proc_penal",
+}
+
+var (
+	// "art. 5 alin. (1) lit. a)", "articolul 1357", "371", "5 (1) a)"
+	citeRe = regexp.MustCompile(`(?i)(?:\bart(?:icolul|\.)?\s*)?\b(` + articleNumberPattern + `)(?:\s*,?\s*(?:alin(?:\.|eatul)?\s*\(?\s*(\d+(?:\^\d+)?)\s*\)?|\(\s*(\d+(?:\^\d+)?)\s*\)))?(?:\s*,?\s*(?:lit(?:\.|era)?\s*)?\(?([a-z](?:\^\d+)?)\))?`)
+	// "din", "al", ... left between the article and the code
+	citeCodePrefixRe = regexp.MustCompile(`(?i)^(?:din|al|a|ale)\s+`)
+)
+
+// parseCitation reads a citation typed by a user, such as "art. 1357 C.civ.",
+// "art. 5 alin. (1) CP" or "371 NCPC". The code may be written before or after
+// the article, abbreviated or in full; codeText is what was written for it,
+// and Code is empty when codeText is empty or names no known code. ok is
+// false when the text holds no article number.
+func parseCitation(cite string) (ref Citation, codeText string, ok bool) {
+	var m []int
+	for _, loc := range citeRe.FindAllStringSubmatchIndex(cite, -1) {
+		// "alin. (1)" names a paragraph, not article 1
+		if !strings.HasSuffix(strings.TrimSpace(cite[:loc[2]]), "(") {
+			m = loc
+			break
+		}
+	}
+	if m == nil {
+		return Citation{}, "", false
+	}
+	ref = Citation{
+		Text:    strings.TrimSpace(cite),
+		Article: normalizeArticleNumber(submatch(cite, m, 1)),
+		Source:  "cite",
+	}
+	ref.Paragraph = submatch(cite, m, 2)
+	if ref.Paragraph == "" {
+		ref.Paragraph = submatch(cite, m, 3)
+	}
+	ref.Letter = strings.ToLower(submatch(cite, m, 4))
+
+	codeText = strings.Trim(cite[:m[0]]+" "+cite[m[1]:], " ,.;:")
+	codeText = citeCodePrefixRe.ReplaceAllString(codeText, "")
+	ref.Code = citationCodeID(codeText)
+	return ref, codeText, true
+}
+
+// citationCodeID maps the code part of a citation, abbreviated ("C.pr.civ.")
+// or spelled out ("Codul de procedură civilă"), to its id in codeFiles.
+func citationCodeID(text string) string {
+	folded := foldWord(text)
+	key := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) {
+			return r
+		}
+		return -1
+	}, folded)
+	id, ok := codeAbbreviations[key]
+	if !ok && strings.Contains(key, "cod") {
+		id = citedCodeID(folded)
+	}
+	if _, known := codeFiles[id]; !known {
+		return ""
+	}
+	return id
+}
+
+// CitationCandidate is an article a citation may refer to.
+type CitationCandidate struct {
+	Code      string `json:"code"`
+	CodeTitle string `json:"codeTitle"`
+	ArticleID string `json:"articleId"`
+	Number    string `json:"number"`
+	Title     string `json:"title"`
+	Paragraph string `json:"paragraph,omitempty"`
+	Letter    string `json:"letter,omitempty"`
+}
+
+// citationMatch is the article, paragraph and letter a citation points to in
+// one code; paragraph and letter are nil when the citation names none.
+type citationMatch struct {
+	code      string
+	article   *Article
+	paragraph *Paragraph
+	letter    *Letter
+}
+
+// matchCitation looks the article, paragraph and letter of ref up in the code
+// id. complete is false when the article exists but the paragraph or letter
+// does not. Articles without numbered paragraphs keep their letters in an
+// unnumbered one.
+func matchCitation(id string, ref Citation) (m citationMatch, found, complete bool) {
+	pc, err := loadParsedCode(id)
+	if err != nil {
+		return m, false, false
+	}
+	m = citationMatch{code: id, article: findArticle(pc, ref.Article)}
+	if m.article == nil {
+		return m, false, false
+	}
+	if ref.Paragraph == "" && ref.Letter == "" {
+		return m, true, true
+	}
+	for i := range m.article.Paragraphs {
+		p := &m.article.Paragraphs[i]
+		if p.Number != ref.Paragraph {
+			continue
+		}
+		if ref.Letter == "" {
+			m.paragraph = p
+			return m, true, true
+		}
+		for j := range p.Letters {
+			if strings.ToLower(p.Letters[j].Letter) == ref.Letter {
+				m.paragraph, m.letter = p, &p.Letters[j]
+				return m, true, true
+			}
+		}
+	}
+	return m, true, false
+}
+
+func (m citationMatch) candidate() CitationCandidate {
+	cd := CitationCandidate{
+		Code: m.code, CodeTitle: codeFiles[m.code].title,
+		ArticleID: m.article.ID, Number: m.article.Number, Title: m.article.Title,
+	}
+	if m.paragraph != nil {
+		cd.Paragraph = m.paragraph.Number
+	}
+	if m.letter != nil {
+		cd.Letter = m.letter.Letter
+	}
+	return cd
+}
+
+// resolveCitationHandler returns the article, paragraph and letter a citation
+// refers to. code is used when the citation names no code. A citation naming
+// an unknown code, or none while several codes have the article, returns the
+// articles it may refer to as candidates, the ones where the cited paragraph
+// and letter exist first.
+func resolveCitationHandler(c *gin.Context) {
+	cite := strings.TrimSpace(c.Query("cite"))
+	if cite == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cite is required"})
+		return
+	}
+	ref, codeText, ok := parseCitation(cite)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no article number in cite"})
+		return
+	}
+	if ref.Code == "" && codeText == "" {
+		if code := c.Query("code"); code != "" {
+			if _, known := codeFiles[code]; !known {
+				c.JSON(http.StatusNotFound, gin.H{"error": "code not found"})
+				return
+			}
+			ref.Code = code
+		}
+	}
+
+	if ref.Code != "" {
+		m, found, complete := matchCitation(ref.Code, ref)
+		switch {
+		case !found:
+			c.JSON(http.StatusNotFound, gin.H{"error": "article not found", "citation": ref, "candidates": []CitationCandidate{}})
+		case !complete:
+			c.JSON(http.StatusNotFound, gin.H{"error": "paragraph or letter not found", "citation": ref, "candidates": []CitationCandidate{m.candidate()}})
+		default:
+			writeCitationMatch(c, ref, m)
+		}
+		return
+	}
+
+	ids := make([]string, 0, len(codeFiles))
+	for id := range codeFiles {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var exact, partial []citationMatch
+	for _, id := range ids {
+		m, found, complete := matchCitation(id, ref)
+		if complete {
+			exact = append(exact, m)
+		} else if found {
+			partial = append(partial, m)
+		}
+	}
+	if codeText == "" && len(exact) == 1 {
+		ref.Code = exact[0].code
+		writeCitationMatch(c, ref, exact[0])
+		return
+	}
+	candidates := []CitationCandidate{}
+	for _, m := range append(exact, partial...) {
+		candidates = append(candidates, m.candidate())
+	}
+	if codeText != "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown code \"" + codeText + "\"", "citation": ref, "candidates": candidates})
+		return
+	}
+	if len(candidates) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "article not found", "citation": ref, "candidates": candidates})
+		return
+	}
+	c.JSON(http.StatusMultipleChoices, gin.H{"error": "ambiguous citation", "citation": ref, "candidates": candidates})
+}
+
+func writeCitationMatch(c *gin.Context, ref Citation, m citationMatch) {
+	ref.Article, ref.TargetID = m.article.Number, m.article.ID
+	out := gin.H{"citation": ref, "article": m.article}
+	if m.paragraph != nil {
+		out["paragraph"] = m.paragraph
+	}
+	if m.letter != nil {
+		out["letter"] = m.letter
+	}
+	c.JSON(http.StatusOK, out)
+}
@@ -0,0 +1,61 @@
+package main
+
+import "testing"
+
+func TestParseCitation(t *testing.T) {
+	tests := []struct {
+		cite     string
+		want     Citation
+		codeText string
+	}{
+		{"art. 1357 C.civ.", Citation{Code: "civil", Article: "1357"}, "C.civ"},
+		{"art. 5 alin. (1) CP", Citation{Code: "penal", Article: "5", Paragraph: "1"}, "CP"},
+		{"371 NCPC", Citation{Code: "proc_civil", Article: "371"}, "NCPC"},
+		{"art. 66 alin. (1) lit. b) din Codul penal", Citation{Code: "penal", Article: "66", Paragraph: "1", Letter: "b"}, "Codul penal"},
+		{"Codul de procedură penală, art. 549^1", Citation{Code: "proc_penal", Article: "549^1"}, "Codul de procedură penală"},
+		{"art. 2.663 Cod civil", Citation{Code: "civil", Article: "2663"}, "Cod civil"},
+		{"5 (2) a) C.pen.", Citation{Code: "penal", Article: "5", Paragraph: "2", Letter: "a"}, "C.pen"},
+		{"art. 471^1 alin. (4) C.proc.civ.", Citation{Code: "proc_civil", Article: "471^1", Paragraph: "4"}, "C.proc.civ"},
+		{"articolul 112 lit. f) Cod penal", Citation{Code: "penal", Article: "112", Letter: "f"}, "Cod penal"},
+		{"art. 21 din Legea nr. 187/2012", Citation{Article: "21"}, "Legea nr. 187/2012"},
+		{"art. 1357", Citation{Article: "1357"}, ""},
+	}
+	for _, tt := range tests {
+		got, codeText, ok := parseCitation(tt.cite)
+		if !ok {
+			t.Errorf("parseCitation(%q) found no article", tt.cite)
+			continue
+		}
+		tt.want.Text, tt.want.Source = tt.cite, "cite"
+		if got != tt.want || codeText != tt.codeText {
+			t.Errorf("parseCitation(%q)\n got  %+v %q\n want %+v %q", tt.cite, got, codeText, tt.want, tt.codeText)
+		}
+	}
+	for _, cite := range []string{"Codul civil", "alin. (1)", ""} {
+		if got, _, ok := parseCitation(cite); ok {
+			t.Errorf("parseCitation(%q) = %+v, want no article", cite, got)
+		}
+	}
+}
+
+func TestCitationCodeID(t *testing.T) {
+	tests := []struct{ text, want string }{
+		{"C.civ.", "civil"},
+		{"NCC", "civil"},
+		{"Codul civil", "civil"},
+		{"C.pen.", "penal"},
+		{"Codul penal", "penal"},
+		{"C.pr.civ.", "proc_civil"},
+		{"Codul de procedură civilă", "proc_civil"},
+		{"NCPP", "proc_penal"},
+		{"Codul de procedura penala", "proc_penal"},
+		{"Codul fiscal", ""},
+		{"Legea nr. 187/2012", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := citationCodeID(tt.text); got != tt.want {
+			t.Errorf("citationCodeID(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"html"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The fields of an article that are searched, in the order of the counts
+// kept by the index.
+const (
+	fieldTitle = iota
+	fieldContent
+	fieldNotes
+	searchFields
+)
+
+var searchFieldNames = [searchFields]string{"title", "content", "notes"}
+
+// a match in the title says more about an article than one in its notes
+var searchFieldWeights = [searchFields]float64{2.5, 1, 0.4}
+
+// BM25 parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// romanianSuffixes are the inflections stripped by stemWord, longest first:
+// definite articles ("-ul", "-ului", "-lor") and plural and case endings.
+var romanianSuffixes = []string{
+	"urilor", "elor", "ilor", "ului", "ul", "uri", "ile", "ele", "lor", "iei", "ii", "ei", "ea", "le", "a", "e", "i", "u",
+}
+
+// stemWord reduces a folded word to a light stem, so that "infracțiune",
+// "infracțiunea" and "infracțiunilor" are the same term. Stems keep at least
+// four letters, which leaves short words alone.
+func stemWord(w string) string {
+	for _, s := range romanianSuffixes {
+		if strings.HasSuffix(w, s) && utf8.RuneCountInString(w)-len(s) >= 4 {
+			return strings.TrimSuffix(w, s)
+		}
+	}
+	return w
+}
+
+// searchToken is a word of a text with its byte offsets.
+type searchToken struct {
+	term       string
+	start, end int
+}
+
+// searchTokens splits a text into stemmed terms, dropping the stop-words and
+// short words that keywordTerms drops too.
+func searchTokens(text string) []searchToken {
+	var tokens []searchToken
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		t := foldWord(text[start:end])
+		if utf8.RuneCountInString(t) >= 3 && !romanianStopWords[t] {
+			tokens = append(tokens, searchToken{term: stemWord(t), start: start, end: end})
+		}
+		start = -1
+	}
+	for i, r := range text {
+		if unicode.IsLetter(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+	}
+	flush(len(text))
+	return tokens
+}
+
+// searchDoc is an indexed article.
+type searchDoc struct {
+	article *Article
+	length  [searchFields]int
+}
+
+type searchPosting struct {
+	doc int
+	tf  [searchFields]int
+}
+
+// searchIndex is the inverted index of the articles of one code.
+type searchIndex struct {
+	docs     []searchDoc
+	postings map[string][]searchPosting
+	totalLen [searchFields]int
+}
+
+func buildSearchIndex(code *ParsedCode) *searchIndex {
+	idx := &searchIndex{postings: map[string][]searchPosting{}}
+	for i := range code.Articles {
+		a := &code.Articles[i]
+		doc := searchDoc{article: a}
+		tf := map[string]*[searchFields]int{}
+		for f, text := range [searchFields]string{a.Title, a.Content, strings.Join(a.Notes, "\n")} {
+			for _, t := range searchTokens(text) {
+				if tf[t.term] == nil {
+					tf[t.term] = &[searchFields]int{}
+				}
+				tf[t.term][f]++
+				doc.length[f]++
+			}
+			idx.totalLen[f] += doc.length[f]
+		}
+		for term, counts := range tf {
+			idx.postings[term] = append(idx.postings[term], searchPosting{doc: len(idx.docs), tf: *counts})
+		}
+		idx.docs = append(idx.docs, doc)
+	}
+	return idx
+}
+
+// each code has its own index, built on first use and rebuilt alone when
+// the code is parsed again or saved
+var (
+	searchMu      sync.Mutex
+	searchIndexes = map[string]*searchIndex{}
+)
+
+// reindexCode replaces the index of a code; a nil code drops it so that it
+// is rebuilt from the stored code on the next search.
+func reindexCode(id string, code *ParsedCode) {
+	var idx *searchIndex
+	if code != nil {
+		idx = buildSearchIndex(code)
+	}
+	searchMu.Lock()
+	defer searchMu.Unlock()
+	if idx == nil {
+		delete(searchIndexes, id)
+		return
+	}
+	searchIndexes[id] = idx
+}
+
+func searchIndexFor(id string) (*searchIndex, error) {
+	searchMu.Lock()
+	idx, ok := searchIndexes[id]
+	searchMu.Unlock()
+	if ok {
+		return idx, nil
+	}
+	pc, err := loadParsedCode(id)
+	if err != nil {
+		return nil, err
+	}
+	idx = buildSearchIndex(pc)
+	searchMu.Lock()
+	searchIndexes[id] = idx
+	searchMu.Unlock()
+	return idx, nil
+}
+
+// SearchResult is an article matching a search, with a snippet of the field
+// that matched best in which the matching words are wrapped in <mark> tags;
+// the rest of the snippet is HTML-escaped.
+type SearchResult struct {
+	Code      string  `json:"code"`
+	ArticleID string  `json:"articleId"`
+	Number    string  `json:"number"`
+	Title     string  `json:"title"`
+	Score     float64 `json:"score"`
+	Field     string  `json:"field"`
+	Snippet   string  `json:"snippet"`
+}
+
+// searchCodes ranks the articles of the given codes against the query with
+// BM25, weighting the fields of scope, and returns the first limit results
+// together with the number of matching articles. Document frequencies and
+// lengths are taken over all the searched codes, so that scores compare
+// across codes.
+func searchCodes(ids []string, query string, scope []int, limit int) ([]SearchResult, int, error) {
+	seen := map[string]bool{}
+	var terms []string
+	for _, t := range searchTokens(query) {
+		if !seen[t.term] {
+			seen[t.term] = true
+			terms = append(terms, t.term)
+		}
+	}
+	if len(terms) == 0 {
+		return []SearchResult{}, 0, nil
+	}
+	indexes := make([]*searchIndex, len(ids))
+	n := 0
+	var totalLen [searchFields]int
+	for i, id := range ids {
+		idx, err := searchIndexFor(id)
+		if err != nil {
+			return nil, 0, err
+		}
+		indexes[i] = idx
+		n += len(idx.docs)
+		for f := range totalLen {
+			totalLen[f] += idx.totalLen[f]
+		}
+	}
+	if n == 0 {
+		return []SearchResult{}, 0, nil
+	}
+	var avgLen [searchFields]float64
+	for f := range avgLen {
+		avgLen[f] = math.Max(float64(totalLen[f])/float64(n), 1)
+	}
+	idf := map[string]float64{}
+	for _, term := range terms {
+		df := 0
+		for _, idx := range indexes {
+			for _, p := range idx.postings[term] {
+				for _, f := range scope {
+					if p.tf[f] > 0 {
+						df++
+						break
+					}
+				}
+			}
+		}
+		idf[term] = math.Log(1 + (float64(n-df)+0.5)/(float64(df)+0.5))
+	}
+
+	type hit struct {
+		code    string
+		article *Article
+		score   float64
+	}
+	var hits []hit
+	for i, idx := range indexes {
+		scores := map[int]float64{}
+		for _, term := range terms {
+			for _, p := range idx.postings[term] {
+				d := idx.docs[p.doc]
+				for _, f := range scope {
+					if p.tf[f] == 0 {
+						continue
+					}
+					tf := float64(p.tf[f])
+					norm := 1 - bm25B + bm25B*float64(d.length[f])/avgLen[f]
+					scores[p.doc] += searchFieldWeights[f] * idf[term] * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+				}
+			}
+		}
+		for doc, score := range scores {
+			hits = append(hits, hit{ids[i], idx.docs[doc].article, score})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score > hits[j].score
+		}
+		if hits[i].code != hits[j].code {
+			return hits[i].code < hits[j].code
+		}
+		return hits[i].article.SortKey < hits[j].article.SortKey
+	})
+	// snippets are only made for the results returned
+	results := []SearchResult{}
+	for _, h := range hits {
+		if len(results) == limit {
+			break
+		}
+		field, snippet := bestSnippet(h.article, seen, scope)
+		results = append(results, SearchResult{
+			Code: h.code, ArticleID: h.article.ID, Number: h.article.Number, Title: h.article.Title,
+			Score: math.Round(h.score*1000) / 1000, Field: searchFieldNames[field], Snippet: snippet,
+		})
+	}
+	return results, len(hits), nil
+}
+
+// words shown around the matches of a snippet
+const snippetWords = 30
+
+// bestSnippet picks the field of scope with the most matching words and
+// returns the passage of it holding the most matches, with the matching
+// words marked. The text is HTML-escaped so that only the marks are markup.
+func bestSnippet(a *Article, terms map[string]bool, scope []int) (int, string) {
+	texts := [searchFields]string{a.Title, a.Content, strings.Join(a.Notes, "\n")}
+	best, bestHits := scope[0], -1
+	var bestTokens []searchToken
+	for _, f := range scope {
+		tokens := searchTokens(texts[f])
+		hits := 0
+		for _, t := range tokens {
+			if terms[t.term] {
+				hits++
+			}
+		}
+		if hits > bestHits {
+			best, bestHits, bestTokens = f, hits, tokens
+		}
+	}
+	text := texts[best]
+
+	// the window of snippetWords tokens with the most matches; the best one
+	// can always start on a match
+	from, most := 0, -1
+	for i := range bestTokens {
+		if !terms[bestTokens[i].term] {
+			continue
+		}
+		hits := 0
+		for j := i; j < len(bestTokens) && j < i+snippetWords; j++ {
+			if terms[bestTokens[j].term] {
+				hits++
+			}
+		}
+		if hits > most {
+			from, most = i, hits
+		}
+	}
+	start, end := 0, len(text)
+	if len(bestTokens) > snippetWords {
+		// start a few words before the first match
+		if from -= 3; from < 0 {
+			from = 0
+		}
+		to := from + snippetWords - 1
+		if to >= len(bestTokens) {
+			to = len(bestTokens) - 1
+		}
+		if from > 0 {
+			start = bestTokens[from].start
+		}
+		if to < len(bestTokens)-1 {
+			end = bestTokens[to].end
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, t := range bestTokens {
+		if t.start < start || t.end > end || !terms[t.term] {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:t.start]))
+		b.WriteString("<mark>" + html.EscapeString(text[t.start:t.end]) + "</mark>")
+		pos = t.end
+	}
+	b.WriteString(html.EscapeString(text[pos:end]))
+	if end < len(text) {
+		b.WriteString("…")
+	}
+	return best, strings.Join(strings.Fields(b.String()), " ")
+}
+
+// searchHandler searches the articles of all codes, or of code, for the
+// words of q. scope limits the search to the "title", "content" or "notes"
+// of the articles; limit caps the number of results (20 by default).
+func searchHandler(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+	var ids []string
+	if code := c.Query("code"); code != "" {
+		if _, ok := codeFiles[code]; !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "code not found"})
+			return
+		}
+		ids = []string{code}
+	} else {
+		for id := range codeFiles {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+	}
+	var scope []int
+	switch s := c.DefaultQuery("scope", "all"); s {
+	case "all":
+		scope = []int{fieldTitle, fieldContent, fieldNotes}
+	case "title":
+		scope = []int{fieldTitle}
+	case "content":
+		scope = []int{fieldContent}
+	case "notes":
+		scope = []int{fieldNotes}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scope must be all, title, content or notes"})
+		return
+	}
+	limit := 20
+	if n, err := strconv.Atoi(c.Query("limit")); err == nil && n > 0 && n <= 100 {
+		limit = n
+	}
+
+	results, total, err := searchCodes(ids, q, scope, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"total": total, "results": results})
+}
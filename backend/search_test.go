@@ -0,0 +1,110 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSearchTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		// case, diacritics and both cedilla forms fold to one stem
+		{"Infracțiunea INFRACŢIUNILOR infractiune", []string{"infractiun", "infractiun", "infractiun"}},
+		{"pedeapsa pedepsele pedepselor", []string{"pedeaps", "pedeps", "pedeps"}},
+		// stop-words, including the words every article uses, and words
+		// under three letters are dropped
+		{"Legea se aplică și în cazul de față", []string{"aplic"}},
+		// stems keep at least four letters
+		{"furtul furtului bunul", []string{"furt", "furt", "bunul"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, tok := range searchTokens(tt.text) {
+			got = append(got, tok.term)
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("searchTokens(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+// useSearchCode indexes a code made of the given articles under the id
+// "test" for the duration of the test.
+func useSearchCode(t *testing.T, articles []Article) {
+	t.Helper()
+	for i := range articles {
+		articles[i].ID = "test/art-" + articles[i].Number
+		articles[i].SortKey = articleSortKey(articles[i].Number)
+	}
+	reindexCode("test", &ParsedCode{ID: "test", Articles: articles})
+	t.Cleanup(func() { reindexCode("test", nil) })
+}
+
+func TestSearchCodesRanking(t *testing.T) {
+	useSearchCode(t, []Article{
+		{Number: "1", Title: "Legalitatea incriminării", Content: "(1) Legea penală prevede faptele care constituie infracțiuni."},
+		{Number: "2", Title: "Tentativa", Content: "(1) Tentativa constă în punerea în executare a intenției de a săvârși infracțiunea, executare care a fost însă întreruptă."},
+		{Number: "3", Title: "Infracțiunea", Content: "(1) Infracțiunea este fapta prevăzută de legea penală, săvârșită cu vinovăție."},
+		{Number: "4", Title: "Omorul", Content: "(1) Uciderea unei persoane se pedepsește cu închisoare."},
+		{Number: "5", Title: "Furtul", Content: "(1) Luarea unui bun mobil din posesia altei persoane se pedepsește.", Notes: []string{"Notă\nInfracțiunea de furt a fost modificată."}},
+	})
+	results, total, err := searchCodes([]string{"test"}, "infracțiunilor", []int{fieldTitle, fieldContent, fieldNotes}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range results {
+		got = append(got, r.Number)
+	}
+	// a title match before content matches, notes last
+	if total != 4 || strings.Join(got, ",") != "3,1,2,5" {
+		t.Errorf("results %q (total %d), want 3,1,2,5", got, total)
+	}
+	if len(results) > 0 && results[0].Field != "title" {
+		t.Errorf("best field of art. 3 %q, want title", results[0].Field)
+	}
+
+	// the rarer word decides the ranking
+	results, _, err = searchCodes([]string{"test"}, "pedepsește persoane omor", []int{fieldTitle, fieldContent, fieldNotes}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Number != "4" {
+		t.Errorf("results %+v, want art. 4 first", results)
+	}
+
+	// scope leaves the other fields out
+	results, total, _ = searchCodes([]string{"test"}, "infracțiunea", []int{fieldNotes}, 10)
+	if total != 1 || results[0].Number != "5" || results[0].Field != "notes" {
+		t.Errorf("notes only: %+v, want art. 5", results)
+	}
+	results, total, _ = searchCodes([]string{"test"}, "și de", []int{fieldContent}, 10)
+	if total != 0 || len(results) != 0 {
+		t.Errorf("stop-words only: %d results", total)
+	}
+}
+
+func TestBestSnippet(t *testing.T) {
+	a := &Article{
+		Title:   "Dispoziții <comune>",
+		Content: "(1) Dacă valoarea bunului este < 1.000 lei & fapta a fost săvârșită de un minor, pedeapsa se reduce.",
+	}
+	all := []int{fieldTitle, fieldContent, fieldNotes}
+	field, snippet := bestSnippet(a, map[string]bool{"pedeaps": true, "valoar": true}, all)
+	want := "(1) Dacă <mark>valoarea</mark> bunului este &lt; 1.000 lei &amp; fapta a fost săvârșită de un minor, <mark>pedeapsa</mark> se reduce."
+	if field != fieldContent || snippet != want {
+		t.Errorf("bestSnippet = %d, %q, want %q", field, snippet, want)
+	}
+	if _, snippet := bestSnippet(a, map[string]bool{"comun": true}, all); snippet != "Dispoziții &lt;<mark>comune</mark>&gt;" {
+		t.Errorf("title snippet %q", snippet)
+	}
+
+	// a long text is cut around the matches
+	a.Content = strings.Repeat("text fără legătură ", 40) + "pedeapsa se reduce" + strings.Repeat(" alt text", 40)
+	_, snippet = bestSnippet(a, map[string]bool{"pedeaps": true}, all)
+	if !strings.HasPrefix(snippet, "…") || !strings.HasSuffix(snippet, "…") || !strings.Contains(snippet, "<mark>pedeapsa</mark>") {
+		t.Errorf("long snippet %q", snippet)
+	}
+}
@@ -0,0 +1,394 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// serializeParsedCode writes a parsed code back as the lines of a code text,
+// so that edits made on the parsed structure can be saved to the source text.
+// For a code produced by parseCodeLines, parsing the lines again with the same
+// grammar gives back the same code:
+//
+//   - placeholder nodes ("Intro", "Untitled", "Unnamed" and the untitled
+//     sections) are left out, the parser adds them again;
+//   - the article title goes on the line after the heading, or on the heading
+//     line when it would otherwise be read as content or a reference;
+//   - notes follow the paragraph they are attached to;
+//   - table of contents entries are written on the lines recorded in them.
+//
+// Text the parser drops, such as the preamble of the act, is not kept; the
+// first line holds the title of the code instead.
+func serializeParsedCode(pc *ParsedCode, g *compiledGrammar) []string {
+	w := &codeWriter{g: g}
+	toc := pc.TableOfContents
+	if w.plainLine(pc.Title) && (toc == nil || len(toc.Entries) == 0 || toc.Entries[0].Line > 1) {
+		w.emit(pc.Title)
+	}
+	if toc != nil {
+		for _, e := range toc.Entries {
+			w.tocEntry(e)
+		}
+	}
+	walkParts(pc, func(p *Part) {
+		w.heading("part", p.Title, p.Subtitle)
+	}, w.book)
+	return w.lines
+}
+
+type codeWriter struct {
+	g     *compiledGrammar
+	lines []string
+}
+
+func (w *codeWriter) emit(lines ...string) {
+	w.lines = append(w.lines, lines...)
+}
+
+// plainLine reports whether a line is read as ordinary text, outside any
+// heading or note.
+func (w *codeWriter) plainLine(line string) bool {
+	return line != "" && !w.g.isHeading(line) && !w.g.note.MatchString(line) && !tocPartRe.MatchString(line)
+}
+
+// tocEntry writes an entry of the table of contents on its recorded line,
+// indented like in the source texts. Part entries are not indented.
+func (w *codeWriter) tocEntry(e TOCEntry) {
+	for len(w.lines) < e.Line-1 {
+		w.emit("")
+	}
+	if e.FromArticle == "" {
+		w.emit(e.Heading)
+		return
+	}
+	text := e.Heading
+	if e.Title != "" {
+		text += " - " + e.Title
+	}
+	text += " art. " + e.FromArticle
+	if e.ToArticle != "" {
+		text += "-" + e.ToArticle
+	}
+	w.emit("    " + text)
+}
+
+// heading writes the heading of a node, its title and subtitle on one line.
+// Titles that do not match the pattern of their level are placeholders and
+// are left out.
+func (w *codeWriter) heading(level, title, subtitle string) {
+	if !w.g.levels[level].MatchString(title) {
+		return
+	}
+	if subtitle != "" {
+		title += " " + subtitle
+	}
+	w.emit("", title)
+}
+
+func (w *codeWriter) book(b *Book) {
+	w.heading("book", b.Title, b.Subtitle)
+	for i := range b.Titles {
+		t := &b.Titles[i]
+		w.heading("title", t.Title, t.Subtitle)
+		for j := range t.Chapters {
+			ch := &t.Chapters[j]
+			w.heading("chapter", ch.Title, ch.Subtitle)
+			for k := range ch.Sections {
+				w.section("section", &ch.Sections[k])
+			}
+		}
+	}
+}
+
+// section writes a section or subsection. The articles of a section come
+// before its subsections, since the parser puts every article after a
+// subsection heading into the subsection.
+func (w *codeWriter) section(level string, s *CodeSection) {
+	w.heading(level, s.Title, s.Subtitle)
+	for i := range s.Articles {
+		w.article(&s.Articles[i])
+	}
+	for i := range s.Subsections {
+		w.section("subsection", &s.Subsections[i])
+	}
+}
+
+// standaloneTitle reports whether an article title can stand on the line after
+// the heading without being read as content, a reference or a heading.
+func (w *codeWriter) standaloneTitle(title string) bool {
+	return w.plainLine(title) && !strings.HasPrefix(title, "(") &&
+		!w.g.references.MatchString(strings.ToLower(title)) && !repealedContentRe.MatchString(title)
+}
+
+func (w *codeWriter) article(a *Article) {
+	heading := "Articolul " + a.Number
+	titleLine := a.Title != "" && w.standaloneTitle(a.Title)
+	if a.Title != "" && !titleLine {
+		heading += " - " + a.Title
+	}
+	w.emit("", heading)
+	if titleLine {
+		w.emit(a.Title)
+	}
+	// references are only told apart by their text, so their position
+	// among the content lines does not matter
+	w.emit(a.References...)
+
+	// a note ends at the next numbered paragraph, so the notes of a
+	// paragraph go after all of its lines; notes found before the first
+	// paragraph are only kept on the article
+	attached := 0
+	for _, p := range a.Paragraphs {
+		attached += len(p.Notes)
+	}
+	if lead := len(a.Notes) - attached; lead > 0 {
+		w.notes(a.Notes[:lead])
+	}
+	var replay Article
+	done := 0
+	if a.Content != "" {
+		for _, line := range strings.Split(a.Content, "\n") {
+			appendParagraphLine(&replay, line)
+			for ; done < len(replay.Paragraphs)-1 && done < len(a.Paragraphs); done++ {
+				w.notes(a.Paragraphs[done].Notes)
+			}
+			w.emit(line)
+		}
+	}
+	for ; done < len(a.Paragraphs); done++ {
+		w.notes(a.Paragraphs[done].Notes)
+	}
+}
+
+func (w *codeWriter) notes(notes []string) {
+	for _, n := range notes {
+		w.emit(strings.Split(n, "\n")...)
+	}
+}
+
+// roundTripDiff serializes pc, parses the text again and returns the text
+// together with the fields of pc that did not survive, at most limit of them.
+func roundTripDiff(pc *ParsedCode, g *compiledGrammar, limit int) ([]string, []string) {
+	lines := serializeParsedCode(pc, g)
+	return lines, parseDiff(pc, lines, g, limit)
+}
+
+// parseDiff parses the lines of a code text and returns the fields of pc that
+// differ in the result, at most limit of them. LastUpdated and ParserVersion
+// are not part of the text and are ignored.
+func parseDiff(pc *ParsedCode, lines []string, g *compiledGrammar, limit int) []string {
+	back := parseCodeLines(lines, pc.ID, pc.Title, g)
+	analyzeParsedCode(back)
+	want, got := *pc, *back
+	want.LastUpdated, got.LastUpdated = "", ""
+	want.ParserVersion, got.ParserVersion = 0, 0
+	return jsonDiff(&want, &got, limit)
+}
+
+// articleContentLines returns the non-empty lines serializeParsedCode writes
+// for an article, leaving out its references.
+func articleContentLines(a *Article, g *compiledGrammar) []string {
+	w := &codeWriter{g: g}
+	bare := *a
+	bare.References = nil
+	w.article(&bare)
+	lines := w.lines[:0]
+	for _, line := range w.lines {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// spliceArticles writes the articles of edited that differ from the ones
+// parsed from the lines of its source text over their lines and leaves every
+// other line as it is, so the preamble, the headings and the untouched
+// articles are kept. Within a rewritten article the blank lines and the
+// references stay where they were. Articles can only be edited this way, not
+// added, removed or moved, and an article is only rewritten when serializing
+// it as parsed gives back its other lines.
+func spliceArticles(lines []string, edited *ParsedCode, g *compiledGrammar) ([]string, error) {
+	source := parseCodeLines(lines, edited.ID, edited.Title, g)
+	if len(source.Articles) != len(edited.Articles) {
+		return nil, fmt.Errorf("the text has %d articles, the edited code %d: articles cannot be added or removed", len(source.Articles), len(edited.Articles))
+	}
+	type splice struct {
+		start, end int
+		lines      []string
+	}
+	var splices []splice
+	for i := range source.Articles {
+		from, to := &source.Articles[i], &edited.Articles[i]
+		if from.ID != to.ID {
+			return nil, fmt.Errorf("art. %s of the text is art. %s in the edited code: articles cannot be added, removed or moved", from.Number, to.Number)
+		}
+		before, after := articleContentLines(from, g), articleContentLines(to, g)
+		if reflect.DeepEqual(before, after) && reflect.DeepEqual(from.References, to.References) {
+			continue
+		}
+		start := source.articleLines[from.ID] - 1
+		end := start + 1
+		for end < len(lines) && !g.isHeading(strings.TrimSpace(lines[end])) {
+			end++
+		}
+		for end > start && strings.TrimSpace(lines[end-1]) == "" {
+			end--
+		}
+
+		// blank lines and references are kept after the content line
+		// they followed; the other lines must be the article as parsed
+		refs := map[string]int{}
+		for _, r := range from.References {
+			refs[r]++
+		}
+		kept := map[string]int{}
+		for _, r := range to.References {
+			kept[r]++
+		}
+		var content []string
+		fixed := map[int][]string{}
+		for _, raw := range lines[start:end] {
+			line := strings.TrimSpace(raw)
+			if line != "" && refs[line] == 0 {
+				content = append(content, line)
+				continue
+			}
+			if line != "" {
+				refs[line]--
+				if kept[line] == 0 {
+					continue
+				}
+				kept[line]--
+			}
+			fixed[len(content)] = append(fixed[len(content)], raw)
+		}
+		if !reflect.DeepEqual(content, before) {
+			return nil, fmt.Errorf("art. %s cannot be written back without changing the lines around it", from.Number)
+		}
+		var out []string
+		for k, line := range after {
+			out = append(append(out, fixed[k]...), line)
+		}
+		for k := len(after); k <= len(content); k++ {
+			out = append(out, fixed[k]...)
+		}
+		for _, r := range to.References {
+			if kept[r] > 0 {
+				kept[r]--
+				out = append(out, r)
+			}
+		}
+		splices = append(splices, splice{start, end, out})
+	}
+	sort.Slice(splices, func(i, j int) bool { return splices[i].start < splices[j].start })
+	out := make([]string, 0, len(lines))
+	next := 0
+	for _, s := range splices {
+		out = append(append(out, lines[next:s.start]...), s.lines...)
+		next = s.end
+	}
+	return append(out, lines[next:]...), nil
+}
+
+// jsonDiff compares the JSON forms of a and b and returns the paths of the
+// values that differ ("books[0].titles[2].title: ..."). Missing values, null
+// and empty arrays or objects are considered equal.
+func jsonDiff(a, b interface{}, limit int) []string {
+	var x, y interface{}
+	for _, v := range []struct {
+		in  interface{}
+		out *interface{}
+	}{{a, &x}, {b, &y}} {
+		data, err := json.Marshal(v.in)
+		if err != nil {
+			return []string{err.Error()}
+		}
+		json.Unmarshal(data, v.out)
+	}
+	var diffs []string
+	var walk func(path string, x, y interface{})
+	walk = func(path string, x, y interface{}) {
+		if len(diffs) >= limit || (jsonEmpty(x) && jsonEmpty(y)) {
+			return
+		}
+		switch xv := x.(type) {
+		case map[string]interface{}:
+			if yv, ok := y.(map[string]interface{}); ok {
+				keys := make([]string, 0, len(xv)+len(yv))
+				for k := range xv {
+					keys = append(keys, k)
+				}
+				for k := range yv {
+					if _, ok := xv[k]; !ok {
+						keys = append(keys, k)
+					}
+				}
+				sort.Strings(keys)
+				for _, k := range keys {
+					walk(strings.TrimPrefix(path+"."+k, "."), xv[k], yv[k])
+				}
+				return
+			}
+		case []interface{}:
+			if yv, ok := y.([]interface{}); ok && len(xv) == len(yv) {
+				for i := range xv {
+					walk(fmt.Sprintf("%s[%d]", path, i), xv[i], yv[i])
+				}
+				return
+			}
+		}
+		if !reflect.DeepEqual(x, y) {
+			diffs = append(diffs, fmt.Sprintf("%s: %s, got %s", path, jsonSnippet(x), jsonSnippet(y)))
+		}
+	}
+	walk("", x, y)
+	return diffs
+}
+
+func jsonEmpty(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// jsonSnippet returns the JSON form of v, shortened for error messages.
+func jsonSnippet(v interface{}) string {
+	data, _ := json.Marshal(v)
+	if r := []rune(string(data)); len(r) > 80 {
+		return string(r[:80]) + "…"
+	}
+	return string(data)
+}
+
+// getSerializedCodeHandler returns the parsed code written as a code text,
+// together with the fields that would change if that text were parsed.
+func getSerializedCodeHandler(c *gin.Context) {
+	id := c.Param("id")
+	pc, err := loadParsedCode(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "code not found"})
+		return
+	}
+	g, err := grammarFor(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	lines, diffs := roundTripDiff(pc, g, 20)
+	if diffs == nil {
+		diffs = []string{}
+	}
+	c.JSON(http.StatusOK, gin.H{"text": strings.Join(lines, "\n") + "\n", "roundTrip": len(diffs) == 0, "differences": diffs})
+}
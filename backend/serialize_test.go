@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+var bundledCodes = []string{"civil", "penal", "proc_civil", "proc_penal"}
+
+// sampleCode exercises the cases the serializer has to lay out with care:
+// a table of contents, missing heading levels, titles that read as content,
+// notes between paragraphs and references.
+const sampleCode = `Codul de test
+
+Partea GENERALĂ
+    Titlul I - Dispoziții generale art. 1-3
+
+Partea GENERALĂ
+
+Titlul I Dispoziții generale
+
+Articolul 1
+Domeniul de aplicare
+(1) Prezentul cod se aplică faptelor săvârșite pe teritoriul României.
+Notă
+Alineatul (1) a fost modificat.
+(2) Legea penală se aplică și faptelor săvârșite în afara teritoriului:
+a) la bordul unei nave;
+b) la bordul unei aeronave.
+(la 01-02-2014, Articolul 1 a fost modificat de Legea nr. 187/2012)
+
+Articolul 2 - (1) Titlu citit ca alineat
+Abrogat.
+
+Secțiunea 1 Aplicarea în timp
+
+Articolul 2^1
+Notă
+Articolul a fost introdus prin Legea nr. 27/2012.
+(1) Text.
+`
+
+func TestSerializeRoundTrip(t *testing.T) {
+	g, err := loadGrammar("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sample := parseCodeLines(strings.Split(sampleCode, "\n"), "test", "Codul de test", g)
+	analyzeParsedCode(sample)
+	if sample.TotalArticles != 3 {
+		t.Fatalf("sample: parsed %d articles, want 3", sample.TotalArticles)
+	}
+	if diffs := roundTripCheck(sample, g); len(diffs) > 0 {
+		t.Errorf("sample: round trip changed the code:\n%s", strings.Join(diffs, "\n"))
+	}
+
+	for _, id := range bundledCodes {
+		t.Run(id, func(t *testing.T) {
+			pc, g := parseBundledCode(t, id)
+			if diffs := roundTripCheck(pc, g); len(diffs) > 0 {
+				t.Errorf("round trip changed the code:\n%s", strings.Join(diffs, "\n"))
+			}
+		})
+	}
+}
+
+func TestSerializeGolden(t *testing.T) {
+	for _, id := range bundledCodes {
+		t.Run(id, func(t *testing.T) {
+			pc, g := parseBundledCode(t, id)
+			got := strings.Join(serializeParsedCode(pc, g), "\n") + "\n"
+			golden := filepath.Join("testdata", "serialize", id+".txt")
+			if *update {
+				os.MkdirAll(filepath.Dir(golden), os.ModePerm)
+				if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			data, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run go test -update to create it)", err)
+			}
+			want := string(data)
+			if got == want {
+				return
+			}
+			gotLines, wantLines := strings.Split(got, "\n"), strings.Split(want, "\n")
+			for i := 0; i < len(gotLines) || i < len(wantLines); i++ {
+				var g, w string
+				if i < len(gotLines) {
+					g = gotLines[i]
+				}
+				if i < len(wantLines) {
+					w = wantLines[i]
+				}
+				if g != w {
+					t.Fatalf("%s:%d differs\n got: %q\nwant: %q", golden, i+1, g, w)
+				}
+			}
+		})
+	}
+}
+
+func parseBundledCode(t *testing.T, id string) (*ParsedCode, *compiledGrammar) {
+	t.Helper()
+	info := codeFiles[id]
+	pc, err := buildParsedCode(info.path, id, info.title)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := grammarFor(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pc, g
+}
+
+func roundTripCheck(pc *ParsedCode, g *compiledGrammar) []string {
+	_, diffs := roundTripDiff(pc, g, 20)
+	return diffs
+}
+
+// Codul penal, art. 65: editing a paragraph rewrites its line only, and the
+// preamble, the amendment lines and the note around it are kept.
+func TestSpliceArticles(t *testing.T) {
+	info := codeFiles["penal"]
+	data, err := os.ReadFile(info.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	g, err := grammarFor("penal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const (
+		before = "(2) În cazul detențiunii pe viață, pedeapsa accesorie constă în interzicerea de către instanță a exercitării drepturilor prevăzute la art. 66 alin. (1) lit. a)-o) sau a unora dintre acestea."
+		after  = "(2) În cazul detențiunii pe viață, pedeapsa accesorie constă în interzicerea exercitării drepturilor prevăzute la art. 66 alin. (1) lit. a)-o)."
+	)
+	edited := parseCodeLines(lines, "penal", info.title, g)
+	walkArticles(edited, func(a *Article) {
+		if a.Number == "65" {
+			a.Content = strings.Replace(a.Content, before, after, 1)
+			a.Paragraphs[1].Text = strings.TrimPrefix(after, "(2) ")
+		}
+	})
+	collectArticles(edited)
+	analyzeParsedCode(edited)
+
+	got, err := spliceArticles(lines, edited, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(lines) {
+		t.Fatalf("spliced text has %d lines, want %d", len(got), len(lines))
+	}
+	changed := 0
+	for i := range lines {
+		want := lines[i]
+		if want == before {
+			want = after
+			changed++
+		}
+		if got[i] != want {
+			t.Errorf("line %d: got %q, want %q", i+1, got[i], want)
+		}
+	}
+	if changed != 1 {
+		t.Fatalf("art. 65 (2) found on %d lines, want 1", changed)
+	}
+	if diffs := parseDiff(edited, got, g, 20); len(diffs) > 0 {
+		t.Errorf("spliced text does not parse back to the edited code:\n%s", strings.Join(diffs, "\n"))
+	}
+
+	edited.Articles = edited.Articles[:len(edited.Articles)-1]
+	if _, err := spliceArticles(lines, edited, g); err == nil {
+		t.Error("spliced a code with an article removed")
+	}
+}
+
+// Saving an edited code as text rewrites the edited line of the source text
+// and keeps the text it replaces as a dated version.
+func TestSaveParsedCodeText(t *testing.T) {
+	const (
+		before = "(1) Legea penală prevede faptele care constituie infracțiuni."
+		after  = "(1) Numai legea penală prevede faptele care constituie infracțiuni."
+	)
+	text := "CODUL DE TEST din 17 iulie 2009\nEMITENT\n\n" + strings.Replace(testCodeText, "(2) Nicio", "(la 01-02-2014, Articolul 1 a fost modificat de Legea nr. 187/2012)\n(2) Nicio", 1)
+	useTestCode(t, text)
+	pc, err := loadParsedCode("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a copy, the cached code must not change
+	var edited ParsedCode
+	data, _ := json.Marshal(pc)
+	json.Unmarshal(data, &edited)
+	walkArticles(&edited, func(a *Article) {
+		if a.Number == "1" {
+			a.Content = strings.Replace(a.Content, before, after, 1)
+			a.Paragraphs[0].Text = strings.TrimPrefix(after, "(1) ")
+		}
+	})
+	body, _ := json.Marshal(&edited)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/save-parsed-code/:id", saveParsedCodeHandler)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/save-parsed-code/test?text=true", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if !strings.Contains(w.Body.String(), `"previousVersion":"2014-02-01"`) {
+		t.Errorf("response %s", w.Body)
+	}
+	got, err := os.ReadFile(codeFiles["test"].path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Replace(text, before, after, 1); string(got) != want {
+		t.Errorf("source text\n%s\nwant\n%s", got, want)
+	}
+	kept, err := os.ReadFile(filepath.Join(versionsDir, "test", "2014-02-01.txt"))
+	if err != nil || string(kept) != text {
+		t.Errorf("previous text kept as %q (%v)", kept, err)
+	}
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Suggestion is an article or heading offered while the user types in the
+// search bar. Kind is "article" or the level of the heading ("book",
+// "title", "chapter", ...); Distance is the number of typos corrected.
+type Suggestion struct {
+	Kind     string `json:"kind"`
+	ID       string `json:"id"`
+	Number   string `json:"number,omitempty"`
+	Title    string `json:"title"`
+	Subtitle string `json:"subtitle,omitempty"`
+	Distance int    `json:"distance"`
+}
+
+// suggestNode is a node of the trie of the folded words of the suggestions.
+// A word ends at a node when the node has postings.
+type suggestNode struct {
+	children map[rune]*suggestNode
+	postings []suggestPosting
+}
+
+// suggestPosting places a word in a suggestion: pos is its position among
+// the words of the suggestion.
+type suggestPosting struct {
+	entry int
+	pos   int
+}
+
+type suggestIndex struct {
+	root    *suggestNode
+	entries []Suggestion
+}
+
+// suggestWords splits a text into folded words. Digits stay in words and a
+// caret joins the index of inserted articles ("86^1").
+func suggestWords(text string) []string {
+	return strings.FieldsFunc(foldWord(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '^'
+	})
+}
+
+func (idx *suggestIndex) add(s Suggestion, text string) {
+	entry := len(idx.entries)
+	idx.entries = append(idx.entries, s)
+	for pos, w := range suggestWords(text) {
+		n := idx.root
+		for _, r := range w {
+			child := n.children[r]
+			if child == nil {
+				child = &suggestNode{children: map[rune]*suggestNode{}}
+				n.children[r] = child
+			}
+			n = child
+		}
+		n.postings = append(n.postings, suggestPosting{entry, pos})
+	}
+}
+
+// buildSuggestIndex indexes the numbers and titles of the articles of a code
+// and the headings above them, in document order. Placeholder headings are
+// left out.
+func buildSuggestIndex(code *ParsedCode) *suggestIndex {
+	idx := &suggestIndex{root: &suggestNode{children: map[rune]*suggestNode{}}}
+	heading := func(kind, id, title, subtitle string) {
+		if title == "" || title == "Intro" || title == "Untitled" || title == "Unnamed" {
+			return
+		}
+		idx.add(Suggestion{Kind: kind, ID: id, Title: title, Subtitle: subtitle}, title+" "+subtitle)
+	}
+	articles := func(list []Article) {
+		for _, a := range list {
+			idx.add(Suggestion{Kind: "article", ID: a.ID, Number: a.Number, Title: a.Title}, a.Number+" "+a.Title)
+		}
+	}
+	book := func(b *Book) {
+		heading("book", b.ID, b.Title, b.Subtitle)
+		for _, t := range b.Titles {
+			heading("title", t.ID, t.Title, t.Subtitle)
+			for _, ch := range t.Chapters {
+				heading("chapter", ch.ID, ch.Title, ch.Subtitle)
+				for _, sec := range ch.Sections {
+					heading("section", sec.ID, sec.Title, sec.Subtitle)
+					articles(sec.Articles)
+					for _, sub := range sec.Subsections {
+						heading("subsection", sub.ID, sub.Title, sub.Subtitle)
+						articles(sub.Articles)
+					}
+				}
+			}
+		}
+	}
+	walkParts(code, func(p *Part) {
+		heading("part", p.ID, p.Title, p.Subtitle)
+	}, book)
+	return idx
+}
+
+// wordMatch is the best match of a query word in a suggestion.
+type wordMatch struct {
+	dist  int
+	exact bool
+	pos   int
+}
+
+// match finds the words starting with q, allowing up to maxDist insertions,
+// deletions or substitutions. It walks the trie computing one row of the
+// Levenshtein table per node, so a branch is dropped as soon as every cell
+// of its row exceeds maxDist. A word matches with the smallest distance
+// between q and any of its prefixes; it is exact when it equals q.
+func (idx *suggestIndex) match(q []rune, maxDist int) map[int]wordMatch {
+	found := map[int]wordMatch{}
+	report := func(n *suggestNode, dist int, exact bool) {
+		for _, p := range n.postings {
+			m, ok := found[p.entry]
+			if !ok || dist < m.dist || (dist == m.dist && exact && !m.exact) || (dist == m.dist && exact == m.exact && p.pos < m.pos) {
+				found[p.entry] = wordMatch{dist, exact, p.pos}
+			}
+		}
+	}
+	first := make([]int, len(q)+1)
+	for j := range first {
+		first[j] = j
+	}
+	var walk func(n *suggestNode, row []int, best int)
+	walk = func(n *suggestNode, row []int, best int) {
+		for r, child := range n.children {
+			next := make([]int, len(q)+1)
+			next[0] = row[0] + 1
+			least := next[0]
+			for j := 1; j <= len(q); j++ {
+				cost := 1
+				if q[j-1] == r {
+					cost = 0
+				}
+				next[j] = min3(row[j]+1, next[j-1]+1, row[j-1]+cost)
+				if next[j] < least {
+					least = next[j]
+				}
+			}
+			b := best
+			if next[len(q)] < b {
+				b = next[len(q)]
+			}
+			if b <= maxDist {
+				report(child, b, next[len(q)] == 0 && next[0] == len(q))
+			}
+			if least <= maxDist || b <= maxDist {
+				walk(child, next, b)
+			}
+		}
+	}
+	walk(idx.root, first, len(q))
+	return found
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// typos corrected in a whole query
+const maxSuggestTypos = 2
+
+// typosAllowed is the edit distance tolerated for a query word: none for
+// numbers and words of up to three letters, one up to six letters, two above.
+func typosAllowed(w string) int {
+	n := utf8.RuneCountInString(w)
+	switch {
+	case w[0] >= '0' && w[0] <= '9', n <= 3:
+		return 0
+	case n <= 6:
+		return 1
+	}
+	return 2
+}
+
+// suggest returns the entries matching every word of the query as a prefix,
+// with at most maxSuggestTypos typos in all, ordered by typos, then exact
+// words, then entries starting with the first word, then document order.
+// "art." before a number and stop-words before the last word are ignored.
+func (idx *suggestIndex) suggest(query string, limit int) []Suggestion {
+	words := suggestWords(query)
+	if len(words) > 1 && (words[0] == "art" || words[0] == "articolul") && unicode.IsDigit(rune(words[1][0])) {
+		words = words[1:]
+	}
+	var kept []string
+	for i, w := range words {
+		if i == len(words)-1 || !romanianStopWords[w] {
+			kept = append(kept, w)
+		}
+	}
+	if len(kept) == 0 {
+		return []Suggestion{}
+	}
+
+	type ranked struct {
+		entry   int
+		dist    int
+		inexact int
+		start   bool
+	}
+	var candidates map[int]*ranked
+	for i, w := range kept {
+		matches := idx.match([]rune(w), typosAllowed(w))
+		next := map[int]*ranked{}
+		for e, m := range matches {
+			r := &ranked{entry: e, start: m.pos == 0}
+			if i > 0 {
+				prev, ok := candidates[e]
+				if !ok {
+					continue
+				}
+				r = prev
+			}
+			if r.dist += m.dist; r.dist > maxSuggestTypos {
+				continue
+			}
+			if !m.exact {
+				r.inexact++
+			}
+			next[e] = r
+		}
+		candidates = next
+	}
+
+	list := make([]*ranked, 0, len(candidates))
+	for _, r := range candidates {
+		list = append(list, r)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.dist != b.dist {
+			return a.dist < b.dist
+		}
+		if a.inexact != b.inexact {
+			return a.inexact < b.inexact
+		}
+		if a.start != b.start {
+			return a.start
+		}
+		return a.entry < b.entry
+	})
+	out := []Suggestion{}
+	for _, r := range list {
+		if len(out) == limit {
+			break
+		}
+		s := idx.entries[r.entry]
+		s.Distance = r.dist
+		out = append(out, s)
+	}
+	return out
+}
+
+// the tries are built on first use and dropped when a code is parsed again
+// or saved, like the search indexes
+var (
+	suggestMu      sync.Mutex
+	suggestIndexes = map[string]*suggestIndex{}
+)
+
+func invalidateSuggestions(id string) {
+	suggestMu.Lock()
+	delete(suggestIndexes, id)
+	suggestMu.Unlock()
+}
+
+func suggestIndexFor(id string) (*suggestIndex, error) {
+	suggestMu.Lock()
+	idx, ok := suggestIndexes[id]
+	suggestMu.Unlock()
+	if ok {
+		return idx, nil
+	}
+	pc, err := loadParsedCode(id)
+	if err != nil {
+		return nil, err
+	}
+	idx = buildSuggestIndex(pc)
+	suggestMu.Lock()
+	suggestIndexes[id] = idx
+	suggestMu.Unlock()
+	return idx, nil
+}
+
+// suggestHandler completes what the user typed in the search bar of a code
+// with article numbers, article titles and headings. limit caps the number
+// of suggestions (10 by default).
+func suggestHandler(c *gin.Context) {
+	idx, err := suggestIndexFor(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "code not found"})
+		return
+	}
+	limit := 10
+	if n, err := strconv.Atoi(c.Query("limit")); err == nil && n > 0 && n <= 50 {
+		limit = n
+	}
+	c.JSON(http.StatusOK, idx.suggest(c.Query("prefix"), limit))
+}
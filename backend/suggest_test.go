@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func suggestTestCode(t *testing.T) *ParsedCode {
+	t.Helper()
+	g, err := loadGrammar("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := []string{
+		"Partea GENERALĂ",
+		"Titlul I Legea penală și limitele ei de aplicare",
+		"Capitolul I Principii generale",
+		"Articolul 1",
+		"Legalitatea incriminării",
+		"(1) Legea penală prevede faptele care constituie infracțiuni.",
+		"Articolul 2",
+		"Legalitatea sancțiunilor de drept penal",
+		"(1) Legea penală prevede pedepsele aplicabile.",
+		"Partea SPECIALĂ",
+		"Titlul I Infracțiuni contra persoanei",
+		"Capitolul I Infracțiuni contra vieții",
+		"Articolul 188",
+		"Omorul",
+		"(1) Uciderea unei persoane se pedepsește cu închisoare de la 10 la 20 de ani.",
+		"Articolul 189",
+		"Omorul calificat",
+		"(1) Omorul săvârșit în vreuna dintre următoarele împrejurări se pedepsește cu detențiune pe viață.",
+		"Articolul 189^1",
+		"Uciderea la cererea victimei",
+		"(1) Uciderea săvârșită la cererea explicită a victimei se pedepsește cu închisoare.",
+	}
+	return parseCodeLines(lines, "penal", "Codul Penal", g)
+}
+
+func TestSuggest(t *testing.T) {
+	idx := buildSuggestIndex(suggestTestCode(t))
+	tests := []struct {
+		query string
+		want  []string
+		typos int
+	}{
+		{"omor", []string{"188", "189"}, 0},
+		{"omorul cal", []string{"189"}, 0},
+		// one typo in a word of six letters
+		{"omurul", []string{"188", "189"}, 1},
+		{"omr", nil, 0},
+		// "art." is dropped and numbers match as prefixes
+		{"art. 189", []string{"189", "189^1"}, 0},
+		{"189^1", []string{"189^1"}, 0},
+		{"188", []string{"188"}, 0},
+		// every word must match; stop-words before the last word are ignored
+		{"legalitatea sanct", []string{"2"}, 0},
+		{"uciderea la victimei", []string{"189^1"}, 0},
+		// headings are suggested by their subtitles, diacritics ignored
+		{"infractiuni", []string{"Titlul I", "Capitolul I"}, 0},
+		{"principii", []string{"Capitolul I"}, 0},
+		{"", nil, 0},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, s := range idx.suggest(tt.query, 10) {
+			if s.Kind == "article" {
+				got = append(got, s.Number)
+			} else {
+				got = append(got, s.Title)
+			}
+			if s.Distance != tt.typos {
+				t.Errorf("suggest(%q): %s with %d typos, want %d", tt.query, s.ID, s.Distance, tt.typos)
+			}
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("suggest(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+	if got := idx.suggest("omorul", 1); len(got) != 1 || got[0].Number != "188" {
+		t.Errorf("suggest with limit 1 = %+v", got)
+	}
+}
+
+// Exact words rank before corrected ones, and entries starting with the
+// query before the ones containing it.
+func TestSuggestRanking(t *testing.T) {
+	idx := &suggestIndex{root: &suggestNode{children: map[rune]*suggestNode{}}}
+	for _, title := range []string{"Furtul calificat", "Furtul", "Furtu", "Tâlhăria și furtul"} {
+		idx.add(Suggestion{Kind: "article", Title: title}, title)
+	}
+	var got []string
+	for _, s := range idx.suggest("furtul", 10) {
+		got = append(got, s.Title)
+	}
+	if want := "Furtul calificat,Furtul,Tâlhăria și furtul,Furtu"; strings.Join(got, ",") != want {
+		t.Errorf("suggest(furtul) = %q, want %s", got, want)
+	}
+}
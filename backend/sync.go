@@ -0,0 +1,331 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// parsedVersion identifies the stored version of a parsed code by a hash of
+// its code_<id>.json. The hash is only computed again when the modification
+// time or size of the file change.
+type parsedVersion struct {
+	etag    string
+	modTime time.Time
+	size    int64
+}
+
+// codeSnapshot records the articles of a version of a code, so that a client
+// holding that version can later be sent only what changed. Outline is a
+// hash of the headings.
+type codeSnapshot struct {
+	ETag     string            `json:"etag"`
+	Outline  string            `json:"outline"`
+	Articles []snapshotArticle `json:"articles"`
+}
+
+type snapshotArticle struct {
+	Key    string `json:"key"`
+	ID     string `json:"id"`
+	Number string `json:"number"`
+	Title  string `json:"title"`
+	Hash   string `json:"hash"`
+}
+
+// snapshots kept per code; clients holding an older version download the
+// whole code again
+const maxSnapshots = 20
+
+var snapshotsDir = filepath.Join(dataDir, "code_snapshots")
+
+// the hex hash inside an ETag
+var etagHashRe = regexp.MustCompile(`^[0-9a-f]{20}$`)
+
+var (
+	versionsMu     sync.Mutex
+	storedVersions = map[string]parsedVersion{}
+)
+
+func contentHash(data []byte, n int) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:n]
+}
+
+// currentParsedVersion returns the version of the stored parsed code id,
+// recording a snapshot of it the first time it is seen.
+func currentParsedVersion(id string) (parsedVersion, error) {
+	jsonPath, st, err := storedCodeFile(id)
+	if err != nil {
+		return parsedVersion{}, err
+	}
+	versionsMu.Lock()
+	v, ok := storedVersions[id]
+	versionsMu.Unlock()
+	if ok && v.modTime.Equal(st.ModTime()) && v.size == st.Size() {
+		return v, nil
+	}
+	data, err := os.ReadFile(jsonPath)
+	if err != nil {
+		return parsedVersion{}, err
+	}
+	v, _, err = recordVersion(id, data, st, false)
+	return v, err
+}
+
+// storedParsedCode reads the stored parsed code id and returns it together
+// with its version, both taken from the same bytes of code_<id>.json.
+func storedParsedCode(id string) (*ParsedCode, parsedVersion, error) {
+	jsonPath, st, err := storedCodeFile(id)
+	if err != nil {
+		return nil, parsedVersion{}, err
+	}
+	data, err := os.ReadFile(jsonPath)
+	if err != nil {
+		return nil, parsedVersion{}, err
+	}
+	v, pc, err := recordVersion(id, data, st, true)
+	return pc, v, err
+}
+
+// storedCodeFile returns the path of the code_<id>.json of a known code.
+// loadParsedCode waits for the startup parsing and writes the file again
+// when it was written by another version of the parser; a code served from
+// the cache whose file is missing is written here.
+func storedCodeFile(id string) (string, os.FileInfo, error) {
+	if _, ok := codeFiles[id]; !ok {
+		return "", nil, fmt.Errorf("unknown code id")
+	}
+	pc, err := loadParsedCode(id)
+	if err != nil {
+		return "", nil, err
+	}
+	jsonPath := filepath.Join(rootDir, "dashbord-react", fmt.Sprintf("code_%s.json", id))
+	st, err := os.Stat(jsonPath)
+	if os.IsNotExist(err) {
+		var data []byte
+		if data, err = json.MarshalIndent(pc, "", "  "); err != nil {
+			return "", nil, err
+		}
+		if err = os.WriteFile(jsonPath, data, 0644); err != nil {
+			return "", nil, err
+		}
+		st, err = os.Stat(jsonPath)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	return jsonPath, st, nil
+}
+
+// recordVersion hashes the bytes of a stored parsed code, saves a snapshot
+// of them if there is none yet and remembers the version. The parsed code is
+// only decoded when a snapshot is written or decode is set. Decoding a whole
+// code takes a while, so versionsMu is only held to remember the version.
+func recordVersion(id string, data []byte, st os.FileInfo, decode bool) (parsedVersion, *ParsedCode, error) {
+	hash := contentHash(data, 20)
+	snapshotPath := filepath.Join(snapshotsDir, id, hash+".json")
+	var pc *ParsedCode
+	_, err := os.Stat(snapshotPath)
+	if err != nil || decode {
+		pc = &ParsedCode{}
+		if err := json.Unmarshal(data, pc); err != nil {
+			return parsedVersion{}, nil, err
+		}
+	}
+	if err != nil {
+		if err := saveSnapshot(id, snapshotPath, snapshotOf(pc, `"`+hash+`"`)); err != nil {
+			fmt.Println("failed to save snapshot of", id, "-", err)
+		}
+	}
+	v := parsedVersion{etag: `"` + hash + `"`, modTime: st.ModTime(), size: st.Size()}
+	versionsMu.Lock()
+	storedVersions[id] = v
+	versionsMu.Unlock()
+	return v, pc, nil
+}
+
+// snapshotKeys returns a key per article of a code: its ID, followed by
+// "#2", "#3"... for the later articles sharing it (laws reproduced inside
+// a code repeat their numbers).
+func snapshotKeys(code *ParsedCode) []string {
+	seen := map[string]int{}
+	keys := make([]string, len(code.Articles))
+	for i, a := range code.Articles {
+		seen[a.ID]++
+		keys[i] = a.ID
+		if n := seen[a.ID]; n > 1 {
+			keys[i] = fmt.Sprintf("%s#%d", a.ID, n)
+		}
+	}
+	return keys
+}
+
+func snapshotOf(code *ParsedCode, etag string) *codeSnapshot {
+	nodes, _ := codeNavigation(code)
+	outline, _ := json.Marshal(outlineTree(nodes, -1, 0, false))
+	s := &codeSnapshot{ETag: etag, Outline: contentHash(outline, 16), Articles: []snapshotArticle{}}
+	for i, key := range snapshotKeys(code) {
+		a := &code.Articles[i]
+		data, _ := json.Marshal(a)
+		s.Articles = append(s.Articles, snapshotArticle{Key: key, ID: a.ID, Number: a.Number, Title: a.Title, Hash: contentHash(data, 16)})
+	}
+	return s
+}
+
+// saveSnapshot writes a snapshot and removes the oldest ones of the code
+// beyond maxSnapshots.
+func saveSnapshot(id, path string, s *codeSnapshot) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) <= maxSnapshots {
+		return err
+	}
+	type stored struct {
+		name    string
+		modTime time.Time
+	}
+	var files []stored
+	for _, e := range entries {
+		if info, err := e.Info(); err == nil {
+			files = append(files, stored{e.Name(), info.ModTime()})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.After(files[j].modTime) })
+	for _, f := range files[minInt(maxSnapshots, len(files)):] {
+		os.Remove(filepath.Join(dir, f.name))
+	}
+	return nil
+}
+
+func loadSnapshot(id, etag string) (*codeSnapshot, error) {
+	data, err := os.ReadFile(filepath.Join(snapshotsDir, id, strings.Trim(etag, `"`)+".json"))
+	if err != nil {
+		return nil, err
+	}
+	var s codeSnapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// parseETag accepts an ETag as sent back by clients, quoted or not and
+// possibly marked weak by the compression, and returns it in the form
+// produced by currentParsedVersion.
+func parseETag(tag string) (string, bool) {
+	tag = strings.Trim(strings.TrimPrefix(strings.TrimSpace(tag), "W/"), `"`)
+	if !etagHashRe.MatchString(tag) {
+		return "", false
+	}
+	return `"` + tag + `"`, true
+}
+
+// notModified sets the ETag and Last-Modified headers of the current version
+// of code id and, when the client already holds that version (If-None-Match,
+// or If-Modified-Since without it), answers 304 and returns true.
+func notModified(c *gin.Context, id string) bool {
+	v, err := currentParsedVersion(id)
+	if err != nil {
+		return false
+	}
+	c.Header("ETag", v.etag)
+	c.Header("Last-Modified", v.modTime.UTC().Format(http.TimeFormat))
+	match := false
+	if inm := c.GetHeader("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			if t, ok := parseETag(tag); (ok && t == v.etag) || strings.TrimSpace(tag) == "*" {
+				match = true
+			}
+		}
+	} else if t, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err == nil {
+		match = !v.modTime.Truncate(time.Second).After(t)
+	}
+	if match {
+		c.Status(http.StatusNotModified)
+	}
+	return match
+}
+
+// getCodeChangesHandler returns the articles added, changed or removed since
+// the version of the code identified by the ETag since. Changed and added
+// articles come whole; removed ones as their ID, number and title.
+// outlineChanged tells the client to reload the outline as well. A version
+// the server no longer knows is answered with 410 Gone. The articles sent
+// and the ETag both come from the stored code_<id>.json.
+func getCodeChangesHandler(c *gin.Context) {
+	id := c.Param("id")
+	since, ok := parseETag(c.Query("since"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an ETag of the code"})
+		return
+	}
+	pc, v, err := storedParsedCode(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "code not found"})
+		return
+	}
+	c.Header("ETag", v.etag)
+	c.Header("Last-Modified", v.modTime.UTC().Format(http.TimeFormat))
+	out := gin.H{
+		"code": id, "etag": v.etag, "since": since,
+		"added": []Article{}, "changed": []Article{}, "removed": []ArticleStub{}, "outlineChanged": false,
+	}
+	if since == v.etag {
+		c.JSON(http.StatusOK, out)
+		return
+	}
+	old, err := loadSnapshot(id, since)
+	if err != nil {
+		c.JSON(http.StatusGone, gin.H{"error": "unknown version, download the whole code", "etag": v.etag})
+		return
+	}
+	cur := snapshotOf(pc, v.etag)
+
+	before := map[string]string{}
+	for _, a := range old.Articles {
+		before[a.Key] = a.Hash
+	}
+	now := map[string]string{}
+	for _, a := range cur.Articles {
+		now[a.Key] = a.Hash
+	}
+	added, changed := []Article{}, []Article{}
+	for i, key := range snapshotKeys(pc) {
+		hash, existed := before[key]
+		switch {
+		case !existed:
+			added = append(added, pc.Articles[i])
+		case hash != now[key]:
+			changed = append(changed, pc.Articles[i])
+		}
+	}
+	removed := []ArticleStub{}
+	for _, a := range old.Articles {
+		if _, ok := now[a.Key]; !ok {
+			removed = append(removed, ArticleStub{ID: a.ID, Number: a.Number, Title: a.Title})
+		}
+	}
+	out["added"], out["changed"], out["removed"] = added, changed, removed
+	out["outlineChanged"] = old.Outline != cur.Outline
+	c.JSON(http.StatusOK, out)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+func syncTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/parsed-code/:id", getParsedCodeHandler)
+	r.GET("/codes/:id/articles/:number", getArticleHandler)
+	r.GET("/codes/:id/changes", getCodeChangesHandler)
+	return r
+}
+
+func TestNotModified(t *testing.T) {
+	useTestCode(t, testCodeText)
+	r := syncTestRouter()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/parsed-code/test", nil))
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" || w.Header().Get("Last-Modified") == "" {
+		t.Fatalf("status %d, ETag %q, Last-Modified %q", w.Code, etag, w.Header().Get("Last-Modified"))
+	}
+	tests := []struct {
+		path   string
+		header string
+		value  string
+		want   int
+	}{
+		{"/parsed-code/test", "If-None-Match", etag, http.StatusNotModified},
+		{"/parsed-code/test", "If-None-Match", "W/" + etag, http.StatusNotModified},
+		{"/parsed-code/test", "If-None-Match", `"0123456789abcdef0123", ` + etag, http.StatusNotModified},
+		{"/parsed-code/test", "If-None-Match", `"0123456789abcdef0123"`, http.StatusOK},
+		{"/parsed-code/test", "If-Modified-Since", w.Header().Get("Last-Modified"), http.StatusNotModified},
+		{"/parsed-code/test", "If-Modified-Since", "Mon, 01 Jan 2001 00:00:00 GMT", http.StatusOK},
+		{"/codes/test/articles/2", "If-None-Match", etag, http.StatusNotModified},
+		{"/codes/test/articles/2", "", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		if tt.header != "" {
+			req.Header.Set(tt.header, tt.value)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s with %s: %s: status %d, want %d", tt.path, tt.header, tt.value, w.Code, tt.want)
+		}
+		if w.Code == http.StatusNotModified && w.Body.Len() > 0 {
+			t.Errorf("%s: 304 with a body", tt.path)
+		}
+	}
+}
+
+// Article 1 is edited, article 2 repealed by removing it and article 3 added.
+func TestCodeChanges(t *testing.T) {
+	jsonPath := useTestCode(t, testCodeText)
+	r := syncTestRouter()
+	first, err := currentParsedVersion("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text := strings.Replace(testCodeText, "(1) Legea penală prevede faptele", "(1) Numai legea penală prevede faptele", 1)
+	text = text[:strings.Index(text, "Articolul 2")] + "Articolul 3\n\nIncriminarea\n(1) Constituie infracțiune fapta prevăzută de legea penală.\n"
+	if err := os.WriteFile(codeFiles["test"].path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(jsonPath)
+	cacheRemove("test")
+
+	get := func(since string) (*httptest.ResponseRecorder, map[string]json.RawMessage) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/codes/test/changes?since="+url.QueryEscape(since), nil))
+		var out map[string]json.RawMessage
+		json.Unmarshal(w.Body.Bytes(), &out)
+		return w, out
+	}
+	numbers := func(raw json.RawMessage) string {
+		var list []struct {
+			Number string `json:"number"`
+		}
+		json.Unmarshal(raw, &list)
+		var n []string
+		for _, a := range list {
+			n = append(n, a.Number)
+		}
+		return strings.Join(n, ",")
+	}
+
+	w, out := get(first.etag)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if got := [3]string{numbers(out["added"]), numbers(out["changed"]), numbers(out["removed"])}; got != [3]string{"3", "1", "2"} {
+		t.Errorf("added, changed, removed = %q, want 3, 1 and 2", got)
+	}
+	second, err := currentParsedVersion("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var etag string
+	json.Unmarshal(out["etag"], &etag)
+	if w.Header().Get("ETag") != second.etag || etag != second.etag {
+		t.Errorf("ETag %s and etag %s, want %s", w.Header().Get("ETag"), out["etag"], second.etag)
+	}
+
+	// the ETag of the current version, quoted or not
+	for _, since := range []string{second.etag, strings.Trim(second.etag, `"`)} {
+		w, out = get(since)
+		if w.Code != http.StatusOK || numbers(out["added"])+numbers(out["changed"])+numbers(out["removed"]) != "" {
+			t.Errorf("since the current version: status %d, %s", w.Code, w.Body)
+		}
+	}
+	if w, _ = get(`"0123456789abcdef0123"`); w.Code != http.StatusGone {
+		t.Errorf("unknown version: status %d, want 410", w.Code)
+	}
+	if w, _ = get("yesterday"); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid ETag: status %d, want 400", w.Code)
+	}
+}
+
+// The stored file of a code served from the cache is written again when it
+// was deleted.
+func TestStoredCodeFileMissing(t *testing.T) {
+	jsonPath := useTestCode(t, testCodeText)
+	if _, err := loadParsedCode("test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(jsonPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := currentParsedVersion("test"); err != nil {
+		t.Fatalf("currentParsedVersion: %v", err)
+	}
+	if _, err := os.Stat(jsonPath); err != nil {
+		t.Errorf("stored file not written: %v", err)
+	}
+}
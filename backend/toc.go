@@ -0,0 +1,308 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TOCEntry is one heading of the table of contents printed at the top of a
+// code, together with the range of articles it declares.
+type TOCEntry struct {
+	Level       string `json:"level"`
+	Heading     string `json:"heading"`
+	Title       string `json:"title,omitempty"`
+	FromArticle string `json:"fromArticle,omitempty"`
+	ToArticle   string `json:"toArticle,omitempty"`
+	Line        int    `json:"line"`
+}
+
+// TableOfContents holds the parsed table of contents and the differences
+// found when comparing it with the body of the code.
+type TableOfContents struct {
+	Entries    []TOCEntry `json:"entries"`
+	Mismatches []string   `json:"mismatches,omitempty"`
+}
+
+var (
+	tocHeadingRe = regexp.MustCompile(`(?i)^(Partea|Cartea|Titlul|Capitolul|Sec[tțţ]iunea|Subsec[tțţ]iunea)\s`)
+	tocPartRe    = regexp.MustCompile(`^Partea\s+(?:\p{Lu}{2,}|[IVX]+|a\s+[IVX]+-a)(?:\s|$)`)
+	tocRangeRe   = regexp.MustCompile(`\s+art\.\s*(\d+(?:\.\d{3})*(?:\^\d+)?)(?:\s*-\s*(\d+(?:\.\d{3})*(?:\^\d+)?))?\s*$`)
+	headingKeyRe = regexp.MustCompile(`(?i)^(Partea|Cartea|Titlul|Capitolul|Sec[tțţ]iunea|Subsec[tțţ]iunea)\s+((?:a\s+)?[IVXLC\d]+(?:-a)?\.?|PRELIMINAR|GENERAL[AĂ]|SPECIAL[AĂ]|UNIC[AĂ]?)`)
+)
+
+// headingLevels maps the heading keyword to the hierarchy level it opens.
+var headingLevels = map[string]string{
+	"partea":       "part",
+	"cartea":       "book",
+	"titlul":       "title",
+	"capitolul":    "chapter",
+	"secțiunea":    "section",
+	"subsecțiunea": "subsection",
+}
+
+// headingKey returns the level and a normalized label ("titlul ii") of a
+// heading line, or empty strings when the line is not a numbered heading.
+func headingKey(line string) (string, string) {
+	m := headingKeyRe.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return "", ""
+	}
+	word := foldCedilla(strings.ToLower(m[1]))
+	word = strings.Replace(word, "sectiunea", "secțiunea", 1)
+	label := foldCedilla(strings.ToLower(strings.TrimSuffix(m[2], ".")))
+	return headingLevels[word], word + " " + label
+}
+
+// headingPartsRe splits a heading line into its label, its designation
+// ("II", "a 2-a", "III^1", "PRELIMINAR") and its name, which may follow a
+// dash ("Titlul II - Infracțiunea") or just a space.
+var headingPartsRe = regexp.MustCompile(`(?i)^(Partea|Cartea|Titlul|Capitolul|Sec[tțţ]iunea|Subsec[tțţ]iunea)\s+((?:a\s+)?(?:[IVXLC]+|\d+)(?:\^\d+)?(?:-a)?|PRELIMINAR|GENERAL[AĂ]|SPECIAL[AĂ]|UNIC[AĂ]?)\.?(?:\s*[-–:]\s+|\s+|$)(.*)$`)
+
+// headingParts is a heading line split by splitHeading.
+type headingParts struct {
+	label    string
+	number   string
+	title    string
+	subtitle string
+}
+
+// splitHeading splits "Secțiunea a 2-a - Aplicarea legii penale în spațiu"
+// into the label "Secțiunea", the number "2", the title "Secțiunea a 2-a"
+// and the subtitle "Aplicarea legii penale în spațiu". Lines that do not
+// start with a numbered heading are kept whole as the title.
+func splitHeading(line string) headingParts {
+	m := headingPartsRe.FindStringSubmatch(line)
+	if m == nil {
+		return headingParts{title: line}
+	}
+	number := strings.TrimSuffix(strings.TrimPrefix(m[2], "a "), "-a")
+	return headingParts{
+		label:    m[1],
+		number:   number,
+		title:    m[1] + " " + strings.Join(strings.Fields(m[2]), " "),
+		subtitle: strings.TrimSpace(m[3]),
+	}
+}
+
+// foldCedilla replaces the legacy cedilla forms of ș and ț with the comma
+// forms used by most of the texts.
+func foldCedilla(s string) string {
+	return strings.NewReplacer("ţ", "ț", "ş", "ș", "Ţ", "Ț", "Ş", "Ș").Replace(s)
+}
+
+// detectTableOfContents finds the table of contents block at the top of a
+// code. TOC entries are indented headings ending in an article range
+// ("Titlul I - Legea penală ... art. 1-14"), possibly wrapped over several
+// lines; "Partea" lines that introduce such entries belong to the block too.
+// It returns the parsed entries and the indexes of the lines to skip.
+func detectTableOfContents(lines []string) (*TableOfContents, map[int]bool) {
+	skip := map[int]bool{}
+	var entries []TOCEntry
+	for i := 0; i < len(lines); i++ {
+		raw := lines[i]
+		line := strings.TrimSpace(raw)
+		if line == "" {
+			continue
+		}
+		if tocPartRe.MatchString(raw) {
+			if next := nextNonEmpty(lines, i+1); next >= 0 && isIndented(lines[next]) {
+				if _, n := readTOCEntry(lines, next); n > 0 {
+					level, _ := headingKey(line)
+					entries = append(entries, TOCEntry{Level: level, Heading: line, Line: i + 1})
+					skip[i] = true
+				}
+			}
+			continue
+		}
+		if !isIndented(raw) || !tocHeadingRe.MatchString(line) {
+			continue
+		}
+		e, n := readTOCEntry(lines, i)
+		if n == 0 {
+			continue
+		}
+		for k := i; k < i+n; k++ {
+			skip[k] = true
+		}
+		entries = append(entries, e)
+		i += n - 1
+	}
+	if len(entries) == 0 {
+		return nil, skip
+	}
+	return &TableOfContents{Entries: entries}, skip
+}
+
+// readTOCEntry reads the TOC entry starting at lines[start], joining the
+// indented continuation lines until the article range is found. It returns
+// the entry and the number of lines used, or 0 when lines[start] does not
+// start a TOC entry.
+func readTOCEntry(lines []string, start int) (TOCEntry, int) {
+	if !tocHeadingRe.MatchString(strings.TrimSpace(lines[start])) {
+		return TOCEntry{}, 0
+	}
+	var parts []string
+	for k := start; k < len(lines) && k < start+4; k++ {
+		raw := lines[k]
+		line := strings.TrimSpace(raw)
+		if line == "" || !isIndented(raw) || (k > start && tocHeadingRe.MatchString(line)) {
+			break
+		}
+		parts = append(parts, line)
+		text := strings.Join(parts, " ")
+		m := tocRangeRe.FindStringSubmatchIndex(text)
+		if m == nil {
+			continue
+		}
+		heading := strings.TrimSpace(text[:m[0]])
+		e := TOCEntry{
+			Heading:     heading,
+			FromArticle: normalizeArticleNumber(text[m[2]:m[3]]),
+			Line:        start + 1,
+		}
+		if m[4] >= 0 {
+			e.ToArticle = normalizeArticleNumber(text[m[4]:m[5]])
+		}
+		if idx := strings.Index(heading, " - "); idx >= 0 {
+			e.Heading = strings.TrimSpace(heading[:idx])
+			e.Title = strings.Join(strings.Fields(heading[idx+3:]), " ")
+		}
+		e.Level, _ = headingKey(e.Heading)
+		return e, k - start + 1
+	}
+	return TOCEntry{}, 0
+}
+
+func isIndented(raw string) bool {
+	return raw != "" && (raw[0] == ' ' || raw[0] == '\t')
+}
+
+func nextNonEmpty(lines []string, from int) int {
+	for k := from; k < len(lines); k++ {
+		if strings.TrimSpace(lines[k]) != "" {
+			return k
+		}
+	}
+	return -1
+}
+
+// headingNode is a heading of the parsed body with the first and last
+// article found beneath it.
+type headingNode struct {
+	level string
+	key   string
+	title string
+	first string
+	last  string
+}
+
+// headingNodes flattens the parsed hierarchy into its headings in document
+// order, recording the article range of each one.
+func headingNodes(code *ParsedCode) []headingNode {
+	var nodes []headingNode
+	var stack []int
+	enter := func(depth int, level, title string) {
+		if len(stack) > depth {
+			stack = stack[:depth]
+		}
+		_, key := headingKey(title)
+		nodes = append(nodes, headingNode{level: level, key: key, title: title})
+		stack = append(stack, len(nodes)-1)
+	}
+	article := func(a Article) {
+		for _, i := range stack {
+			if nodes[i].first == "" {
+				nodes[i].first = a.Number
+			}
+			nodes[i].last = a.Number
+		}
+	}
+	book := func(depth int, b *Book) {
+		enter(depth, "book", b.Title)
+		for _, t := range b.Titles {
+			enter(depth+1, "title", t.Title)
+			for _, ch := range t.Chapters {
+				enter(depth+2, "chapter", ch.Title)
+				for _, sec := range ch.Sections {
+					enter(depth+3, "section", sec.Title)
+					for _, a := range sec.Articles {
+						article(a)
+					}
+					for _, sub := range sec.Subsections {
+						enter(depth+4, "subsection", sub.Title)
+						for _, a := range sub.Articles {
+							article(a)
+						}
+					}
+				}
+			}
+		}
+	}
+	walkParts(code, func(p *Part) {
+		enter(0, "part", p.Title)
+	}, func(b *Book) {
+		if b.Part != "" {
+			book(1, b)
+		} else {
+			book(0, b)
+		}
+	})
+	return nodes
+}
+
+// checkTableOfContents compares the declared article ranges of the table of
+// contents with the articles actually found under each heading and records
+// the differences in code.TableOfContents.Mismatches.
+func checkTableOfContents(code *ParsedCode) {
+	toc := code.TableOfContents
+	if toc == nil {
+		return
+	}
+	toc.Mismatches = nil
+	nodes := headingNodes(code)
+	j := 0
+	for _, e := range toc.Entries {
+		_, key := headingKey(e.Heading)
+		found := -1
+		for k := j; k < len(nodes); k++ {
+			if nodes[k].level == e.Level && nodes[k].key == key {
+				found = k
+				break
+			}
+		}
+		if found < 0 {
+			toc.Mismatches = append(toc.Mismatches, fmt.Sprintf("line %d: %s not found in the body", e.Line, e.Heading))
+			addDiagnostic(code, e.Line, "warning", "table of contents: %s not found in the body", e.Heading)
+			continue
+		}
+		j = found + 1
+		if e.FromArticle == "" {
+			continue
+		}
+		n := nodes[found]
+		to := e.ToArticle
+		if to == "" {
+			to = e.FromArticle
+		}
+		first, last := normalizeArticleNumber(n.first), normalizeArticleNumber(n.last)
+		// articles inserted after the last declared one ("112^1" after
+		// "art. 107-112") are not listed in the table of contents
+		if first != e.FromArticle || articleSortKey(last)/1000 != articleSortKey(to)/1000 {
+			toc.Mismatches = append(toc.Mismatches, fmt.Sprintf("line %d: %s declares art. %s, found art. %s",
+				e.Line, e.Heading, articleRange(e.FromArticle, to), articleRange(first, last)))
+			addDiagnostic(code, e.Line, "warning", "table of contents: %s declares art. %s, found art. %s",
+				e.Heading, articleRange(e.FromArticle, to), articleRange(first, last))
+		}
+	}
+}
+
+func articleRange(from, to string) string {
+	switch {
+	case from == "":
+		return "none"
+	case from == to:
+		return from
+	}
+	return from + "-" + to
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The top of Codul penal: a part line, then the indented table of contents
+// with a wrapped entry, then the body.
+var tocCodeLines = []string{
+	"CODUL PENAL din 17 iulie 2009 (LEGEA nr. 286/2009)",
+	"",
+	"Partea GENERALĂ",
+	"",
+	"      Titlul I - Legea penală și limitele ei de aplicare          art. 1-4",
+	"    Capitolul I - Principii generale                              art. 1-2",
+	"    Capitolul II - Aplicarea legii penale                         art. 3-4",
+	"     Secțiunea a 2-a - Circumstanțele atenuante și",
+	"                        circumstanțele agravante                  art. 3-4",
+	"      Titlul II - Infracțiunea                                    art. 5",
+	"",
+	"Partea GENERALĂ",
+	"Titlul I Legea penală și limitele ei de aplicare",
+	"Capitolul I Principii generale",
+	"Articolul 1",
+	"Legalitatea incriminării",
+	"(1) Legea penală prevede faptele care constituie infracțiuni.",
+	"Articolul 2",
+	"Legalitatea sancțiunilor de drept penal",
+	"(1) Legea penală prevede pedepsele aplicabile.",
+	"Capitolul II Aplicarea legii penale",
+	"Secțiunea a 2-a Circumstanțele atenuante și circumstanțele agravante",
+	"Articolul 3",
+	"Activitatea legii penale",
+	"(1) Legea penală se aplică infracțiunilor săvârșite în timpul cât ea se află în vigoare.",
+	"Articolul 4",
+	"Aplicarea legii penale de dezincriminare",
+	"(1) Legea penală nu se aplică faptelor săvârșite sub legea veche.",
+	"Titlul II Infracțiunea",
+	"Capitolul I Dispoziții generale",
+	"Articolul 15",
+	"Trăsăturile esențiale ale infracțiunii",
+	"(1) Infracțiunea este fapta prevăzută de legea penală.",
+}
+
+func TestDetectTableOfContents(t *testing.T) {
+	toc, skip := detectTableOfContents(tocCodeLines)
+	if toc == nil {
+		t.Fatal("no table of contents found")
+	}
+	want := []TOCEntry{
+		{Level: "part", Heading: "Partea GENERALĂ", Line: 3},
+		{Level: "title", Heading: "Titlul I", Title: "Legea penală și limitele ei de aplicare", FromArticle: "1", ToArticle: "4", Line: 5},
+		{Level: "chapter", Heading: "Capitolul I", Title: "Principii generale", FromArticle: "1", ToArticle: "2", Line: 6},
+		{Level: "chapter", Heading: "Capitolul II", Title: "Aplicarea legii penale", FromArticle: "3", ToArticle: "4", Line: 7},
+		{Level: "section", Heading: "Secțiunea a 2-a", Title: "Circumstanțele atenuante și circumstanțele agravante", FromArticle: "3", ToArticle: "4", Line: 8},
+		{Level: "title", Heading: "Titlul II", Title: "Infracțiunea", FromArticle: "5", Line: 10},
+	}
+	if !reflect.DeepEqual(toc.Entries, want) {
+		t.Errorf("entries\n%+v\nwant\n%+v", toc.Entries, want)
+	}
+	for i := range tocCodeLines {
+		if want := i >= 2 && i <= 9 && i != 3; skip[i] != want {
+			t.Errorf("line %d skipped %v, want %v", i+1, skip[i], want)
+		}
+	}
+}
+
+// The body of the code is parsed without the table of contents, and the
+// ranges it declares are compared with the articles found.
+func TestCheckTableOfContents(t *testing.T) {
+	g, err := loadGrammar("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc := parseCodeLines(tocCodeLines, "penal", "Codul Penal", g)
+	if len(pc.Parts) != 1 || len(pc.Books) != 1 || len(pc.Books[0].Titles) != 2 {
+		t.Fatalf("parsed %d parts, %d books, want the body only", len(pc.Parts), len(pc.Books))
+	}
+	if pc.TableOfContents == nil {
+		t.Fatal("no table of contents")
+	}
+	want := []string{"line 10: Titlul II declares art. 5, found art. 15"}
+	if !reflect.DeepEqual(pc.TableOfContents.Mismatches, want) {
+		t.Errorf("mismatches %q, want %q", pc.TableOfContents.Mismatches, want)
+	}
+	found := false
+	for _, d := range pc.Diagnostics {
+		if d.Line == 10 && strings.Contains(d.Message, "table of contents") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("no diagnostic for the mismatch: %+v", pc.Diagnostics)
+	}
+}
+
+func TestSplitHeading(t *testing.T) {
+	tests := []struct {
+		line string
+		want headingParts
+	}{
+		{"Secțiunea a 2-a - Aplicarea legii penale în spațiu", headingParts{"Secțiunea", "2", "Secțiunea a 2-a", "Aplicarea legii penale în spațiu"}},
+		{"Titlul II Infracțiunea", headingParts{"Titlul", "II", "Titlul II", "Infracțiunea"}},
+		{"Capitolul III^1", headingParts{"Capitolul", "III^1", "Capitolul III^1", ""}},
+		{"Titlul PRELIMINAR Despre legea civilă", headingParts{"Titlul", "PRELIMINAR", "Titlul PRELIMINAR", "Despre legea civilă"}},
+		{"Cartea a II-a: Despre familie", headingParts{"Cartea", "II", "Cartea a II-a", "Despre familie"}},
+		{"§ 1. Intervenția voluntară", headingParts{title: "§ 1. Intervenția voluntară"}},
+	}
+	for _, tt := range tests {
+		if got := splitHeading(tt.line); got != tt.want {
+			t.Errorf("splitHeading(%q) = %+v, want %+v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestHeadingKey(t *testing.T) {
+	tests := []struct{ line, level, key string }{
+		{"Titlul II - Infracțiunea", "title", "titlul ii"},
+		{"Secţiunea a 2-a", "section", "secțiunea a 2-a"},
+		{"SECȚIUNEA 1", "section", "secțiunea 1"},
+		{"Partea GENERALĂ", "part", "partea generală"},
+		{"Titlul executoriu", "", ""},
+	}
+	for _, tt := range tests {
+		if level, key := headingKey(tt.line); level != tt.level || key != tt.key {
+			t.Errorf("headingKey(%q) = %q, %q, want %q, %q", tt.line, level, key, tt.level, tt.key)
+		}
+	}
+}
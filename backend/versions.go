@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Older texts of a code are kept next to the current one as
+// codurileactualizate/versiuni/<code id>/<YYYY-MM-DD>.txt, the date being the
+// day from which that text was in force.
+var versionsDir = filepath.Join(codesTextDir, "versiuni")
+
+// CodeVersion is one dated text of a code.
+type CodeVersion struct {
+	Date    string `json:"date"`
+	Current bool   `json:"current"`
+	path    string
+}
+
+var (
+	versionDateRe      = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	amendmentLineDate  = regexp.MustCompile(`(?m)^\(la\s+(\d{2})-(\d{2})-(\d{4}),`)
+	currentVersionMu   sync.Mutex
+	currentVersionDate = map[string]struct {
+		mod  time.Time
+		date string
+	}{}
+)
+
+// inForceSince returns the date of the latest change recorded in a code text,
+// which is the day the text came into force, or the day the file was last
+// modified when it records no change. It is cached per file until the file is
+// modified.
+func inForceSince(path string) string {
+	st, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	currentVersionMu.Lock()
+	defer currentVersionMu.Unlock()
+	if c, ok := currentVersionDate[path]; ok && c.mod.Equal(st.ModTime()) {
+		return c.date
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	latest := ""
+	for _, m := range amendmentLineDate.FindAllStringSubmatch(string(data), -1) {
+		if d := m[3] + "-" + m[2] + "-" + m[1]; d > latest {
+			latest = d
+		}
+	}
+	if latest == "" {
+		latest = st.ModTime().Format("2006-01-02")
+	}
+	currentVersionDate[path] = struct {
+		mod  time.Time
+		date string
+	}{st.ModTime(), latest}
+	return latest
+}
+
+// codeVersions lists the texts of a code sorted by date. The current text
+// from codeFiles is always included, after the texts uploaded for its date.
+func codeVersions(id string) ([]CodeVersion, error) {
+	info, ok := codeFiles[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown code id")
+	}
+	versions := []CodeVersion{{Date: inForceSince(info.path), Current: true, path: info.path}}
+	entries, _ := os.ReadDir(filepath.Join(versionsDir, id))
+	for _, e := range entries {
+		date := strings.TrimSuffix(e.Name(), ".txt")
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".txt") || !versionDateRe.MatchString(date) {
+			continue
+		}
+		versions = append(versions, CodeVersion{Date: date, path: filepath.Join(versionsDir, id, e.Name())})
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		if versions[i].Date != versions[j].Date {
+			return versions[i].Date < versions[j].Date
+		}
+		return !versions[i].Current && versions[j].Current
+	})
+	return versions, nil
+}
+
+// versionAt returns the version of a code in force on date (YYYY-MM-DD): the
+// latest text dated on or before it. On the day of the current text, the
+// current text is preferred to an uploaded one; a text uploaded for a later
+// day, such as an upcoming consolidated text, is served from that day on.
+func versionAt(id, date string) (*CodeVersion, error) {
+	versions, err := codeVersions(id)
+	if err != nil {
+		return nil, err
+	}
+	var v *CodeVersion
+	for i := range versions {
+		if versions[i].Date <= date {
+			v = &versions[i]
+		}
+	}
+	if v == nil {
+		return nil, fmt.Errorf("no version in force on %s", date)
+	}
+	return v, nil
+}
+
+// loadParsedCodeAt returns the version of a code in force on date.
+func loadParsedCodeAt(id, date string) (*ParsedCode, *CodeVersion, error) {
+	v, err := versionAt(id, date)
+	if err != nil {
+		return nil, nil, err
+	}
+	if v.Current {
+		pc, err := loadParsedCode(id)
+		return pc, v, err
+	}
+	key := id + "@" + v.Date
+	if pc, ok := cacheGet(key); ok {
+		return pc, v, nil
+	}
+	pc, err := buildParsedCode(v.path, id, codeFiles[id].title)
+	if err != nil {
+		return nil, nil, err
+	}
+	pc.LastUpdated = v.Date
+	cacheAdd(key, pc)
+	return pc, v, nil
+}
+
+// replaceCodeText makes text the current text of a code. It is written to a
+// temporary file renamed over the old text, which is kept as the version
+// dated by the day it came into force unless a text of that day is already
+// stored. It returns the date of that version.
+func replaceCodeText(id string, text []byte) (string, error) {
+	info, ok := codeFiles[id]
+	if !ok {
+		return "", fmt.Errorf("unknown code id")
+	}
+	old, err := os.ReadFile(info.path)
+	if err != nil {
+		return "", err
+	}
+	date := inForceSince(info.path)
+	dir := filepath.Join(versionsDir, id)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", err
+	}
+	kept := filepath.Join(dir, date+".txt")
+	if _, err := os.Stat(kept); os.IsNotExist(err) {
+		if err := os.WriteFile(kept, old, 0644); err != nil {
+			return "", err
+		}
+		cacheRemove(id + "@" + date)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(info.path), "."+filepath.Base(info.path)+"-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(text); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	return date, os.Rename(tmp.Name(), info.path)
+}
+
+// listCodeVersionsHandler lists the dates for which a text of the code exists.
+func listCodeVersionsHandler(c *gin.Context) {
+	versions, err := codeVersions(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "code not found"})
+		return
+	}
+	c.JSON(http.StatusOK, versions)
+}
+
+// uploadCodeVersionHandler stores a dated text of a code sent as the "file"
+// form field, the date being given in the "date" field.
+func uploadCodeVersionHandler(c *gin.Context) {
+	id := c.Param("id")
+	if _, ok := codeFiles[id]; !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "code not found"})
+		return
+	}
+	date := c.PostForm("date")
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "date must be YYYY-MM-DD"})
+		return
+	}
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing file"})
+		return
+	}
+	dir := filepath.Join(versionsDir, id)
+	os.MkdirAll(dir, os.ModePerm)
+	if err := c.SaveUploadedFile(file, filepath.Join(dir, date+".txt")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save"})
+		return
+	}
+	cacheRemove(id + "@" + date)
+	c.JSON(http.StatusOK, gin.H{"id": id, "date": date})
+}
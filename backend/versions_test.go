@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// A current text recording no change is dated by its last modification. It
+// takes the place of a text uploaded for the same day, and a text uploaded for
+// a later day takes its place from that day on.
+func TestVersionAtUndatedCurrentText(t *testing.T) {
+	dir := t.TempDir()
+	current := filepath.Join(dir, "test.txt")
+	if err := os.WriteFile(current, []byte("Articolul 1\n\nLegea penală se aplică infracțiunilor săvârșite în timpul cât ea se află în vigoare.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modified := time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local)
+	if err := os.Chtimes(current, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+	uploads := filepath.Join(dir, "versiuni")
+	if err := os.MkdirAll(filepath.Join(uploads, "test"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, date := range []string{"2014-02-01", "2024-03-01", "2025-01-01"} {
+		if err := os.WriteFile(filepath.Join(uploads, "test", date+".txt"), []byte("Articolul 1\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	oldVersionsDir := versionsDir
+	versionsDir = uploads
+	codeFiles["test"] = codeFile{path: current, title: "Test"}
+	defer func() {
+		versionsDir = oldVersionsDir
+		delete(codeFiles, "test")
+	}()
+
+	versions, err := codeVersions("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 4 || versions[1].Current || !versions[2].Current || versions[2].Date != "2024-03-01" {
+		t.Errorf("versions %+v, want the current text dated 2024-03-01 after the upload of that day", versions)
+	}
+	tests := []struct {
+		date    string
+		want    string
+		current bool
+	}{
+		{"2014-01-31", "", false},
+		{"2020-06-15", "2014-02-01", false},
+		{"2024-03-01", "2024-03-01", true},
+		{"2024-12-31", "2024-03-01", true},
+		{"2025-01-01", "2025-01-01", false},
+		{time.Now().Format("2006-01-02"), "2025-01-01", false},
+	}
+	for _, tt := range tests {
+		v, err := versionAt("test", tt.date)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("versionAt(%s) = %+v, want none", tt.date, v)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("versionAt(%s): %v", tt.date, err)
+			continue
+		}
+		if v.Date != tt.want || v.Current != tt.current {
+			t.Errorf("versionAt(%s) = %s current=%v, want %s current=%v", tt.date, v.Date, v.Current, tt.want, tt.current)
+		}
+	}
+}
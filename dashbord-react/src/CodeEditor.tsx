@@ -13,6 +13,7 @@ interface Article {
 interface CodeSection {
   id: string;
   title: string;
+  subtitle?: string;
   subsections: CodeSection[];
   articles: Article[];
 }
@@ -20,18 +21,21 @@ interface CodeSection {
 interface Chapter {
   id: string;
   title: string;
+  subtitle?: string;
   sections: CodeSection[];
 }
 
 interface CodeTitle {
   id: string;
   title: string;
+  subtitle?: string;
   chapters: Chapter[];
 }
 
 interface Book {
   id: string;
   title: string;
+  subtitle?: string;
   titles: CodeTitle[];
 }
 
@@ -47,6 +51,9 @@ interface CodeInfo {
   lastUpdated: string;
 }
 
+const headingText = (h: { title: string; subtitle?: string }) =>
+  h.subtitle ? `${h.title} - ${h.subtitle}` : h.title;
+
 export default function CodeEditor() {
   const [codes, setCodes] = useState<CodeInfo[]>([]);
   const [active, setActive] = useState("");
@@ -114,12 +121,14 @@ export default function CodeEditor() {
     }
   };
 
-  const updateTitle = (id: string, value: string) => {
+  // headings are edited by their name; the label and number ("Capitolul
+  // III") come from the source text
+  const updateSubtitle = (id: string, value: string) => {
     if (!structure) return;
     const walk = (sections: CodeSection[]): boolean => {
       for (const sec of sections) {
         if (sec.id === id) {
-          sec.title = value;
+          sec.subtitle = value;
           return true;
         }
         if (walk(sec.subsections)) return true;
@@ -128,19 +137,19 @@ export default function CodeEditor() {
     };
     for (const b of structure.books || []) {
       if (b.id === id) {
-        b.title = value;
+        b.subtitle = value;
         setStructure({ ...structure });
         return;
       }
       for (const t of b.titles || []) {
         if (t.id === id) {
-          t.title = value;
+          t.subtitle = value;
           setStructure({ ...structure });
           return;
         }
         for (const ch of t.chapters || []) {
           if (ch.id === id) {
-            ch.title = value;
+            ch.subtitle = value;
             setStructure({ ...structure });
             return;
           }
@@ -232,8 +241,8 @@ export default function CodeEditor() {
           <>
             <input
               className="border p-1 flex-1"
-              value={sec.title}
-              onChange={(e) => updateTitle(sec.id, e.target.value)}
+              value={sec.subtitle || ""}
+              onChange={(e) => updateSubtitle(sec.id, e.target.value)}
             />
             <Button
               size="sm"
@@ -247,7 +256,7 @@ export default function CodeEditor() {
           </>
         ) : (
           <>
-            <h5 className="font-semibold flex-1">{sec.title}</h5>
+            <h5 className="font-semibold flex-1">{headingText(sec)}</h5>
             <Button
               variant="ghost"
               size="sm"
@@ -272,8 +281,8 @@ export default function CodeEditor() {
           <>
             <input
               className="border p-1 flex-1"
-              value={ch.title}
-              onChange={(e) => updateTitle(ch.id, e.target.value)}
+              value={ch.subtitle || ""}
+              onChange={(e) => updateSubtitle(ch.id, e.target.value)}
             />
             <Button
               size="sm"
@@ -287,7 +296,7 @@ export default function CodeEditor() {
           </>
         ) : (
           <>
-            <h4 className="font-semibold flex-1">{ch.title}</h4>
+            <h4 className="font-semibold flex-1">{headingText(ch)}</h4>
             <Button
               variant="ghost"
               size="sm"
@@ -309,8 +318,8 @@ export default function CodeEditor() {
           <>
             <input
               className="border p-1 flex-1"
-              value={t.title}
-              onChange={(e) => updateTitle(t.id, e.target.value)}
+              value={t.subtitle || ""}
+              onChange={(e) => updateSubtitle(t.id, e.target.value)}
             />
             <Button
               size="sm"
@@ -324,7 +333,7 @@ export default function CodeEditor() {
           </>
         ) : (
           <>
-            <h3 className="font-semibold flex-1">{t.title}</h3>
+            <h3 className="font-semibold flex-1">{headingText(t)}</h3>
             <Button
               variant="ghost"
               size="sm"
@@ -346,8 +355,8 @@ export default function CodeEditor() {
           <>
             <input
               className="border p-1 flex-1"
-              value={b.title}
-              onChange={(e) => updateTitle(b.id, e.target.value)}
+              value={b.subtitle || ""}
+              onChange={(e) => updateSubtitle(b.id, e.target.value)}
             />
             <Button
               size="sm"
@@ -361,7 +370,7 @@ export default function CodeEditor() {
           </>
         ) : (
           <>
-            <h2 className="font-bold flex-1">{b.title}</h2>
+            <h2 className="font-bold flex-1">{headingText(b)}</h2>
             <Button
               variant="ghost"
               size="sm"